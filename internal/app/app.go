@@ -2,42 +2,342 @@ package app
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/universal-development/go-getter-file/internal/config"
 	"github.com/universal-development/go-getter-file/internal/processor"
 )
 
+// Output formats supported by the --output flag
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// commands maps subcommand names to their implementation
+var commands = map[string]func(ctx context.Context, env *env, paths []string) error{
+	"get":      runGet,
+	"lock":     runLock,
+	"plan":     runPlan,
+	"validate": runValidate,
+	"migrate":  runMigrate,
+	"list":     runList,
+	"clean":    runClean,
+}
+
+// options holds the parsed command line flags
+type options struct {
+	parallelism int
+	maxFetches  int
+	retries     int
+	timeout     time.Duration
+	quiet       bool
+	output      string
+	vars        varsFlag
+	cacheDir    string
+	policy      string
+	outputRoot  string
+	conflicts   string
+	olderThan   time.Duration
+	report      string
+	logLevel    string
+	logFormat   string
+	logFile     string
+	destFromCwd bool
+	locked      bool
+	dryRun      bool
+	help        bool
+	version     bool
+}
+
+// varsFlag collects repeated --var key=value flags
+type varsFlag map[string]string
+
+func (v *varsFlag) String() string {
+	return fmt.Sprint(map[string]string(*v))
+}
+
+func (v *varsFlag) Set(value string) error {
+	name, val, ok := strings.Cut(value, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	if *v == nil {
+		*v = make(varsFlag)
+	}
+	(*v)[name] = val
+	return nil
+}
+
+// env is the state shared by all subcommands
+type env struct {
+	version   string
+	stdout    io.Writer
+	opts      options
+	overrides config.Overrides
+	level     slog.Level
+	logOut    io.Writer
+	logger    *slog.Logger
+}
+
 // Run executes the CLI application logic using the provided context and arguments.
 func Run(ctx context.Context, version string, args []string, stdout io.Writer) error {
-	if len(args) == 0 {
+	e := &env{version: version, stdout: stdout}
+
+	positional, err := e.parseArgs(args)
+	if err != nil {
 		printUsage(stdout, version)
-		return fmt.Errorf("no configuration files or directories specified")
+		return err
 	}
 
-	if args[0] == "-h" || args[0] == "--help" {
+	if e.opts.help || (len(positional) > 0 && positional[0] == "help") {
 		printUsage(stdout, version)
 		return nil
 	}
 
-	if args[0] == "-v" || args[0] == "--version" {
-		fmt.Fprintf(stdout, "go-getter-file version %s\n", version)
-		return nil
+	if e.opts.version || (len(positional) > 0 && positional[0] == "version") {
+		return runVersion(e)
 	}
 
-	fmt.Fprintf(stdout, "go-getter-file version %s\n", version)
+	if len(positional) > 0 && positional[0] == "schema" {
+		return runSchema(e, positional[1:])
+	}
 
-	proc, err := processor.New(args)
+	closeLog, err := e.setupLogging()
 	if err != nil {
 		return err
 	}
+	defer closeLog()
 
-	if err := proc.Process(ctx); err != nil {
-		return err
+	if len(positional) == 0 {
+		printUsage(stdout, version)
+		return fmt.Errorf("no configuration files or directories specified")
 	}
 
-	fmt.Fprintln(stdout, "\nAll configuration files processed successfully!")
-	return nil
+	if positional[0] == "cache" {
+		return runCache(e, positional[1:])
+	}
+
+	command, positional := splitCommand(positional)
+	if len(positional) == 0 {
+		return fmt.Errorf("%s: no configuration files or directories specified", command)
+	}
+
+	return commands[command](ctx, e, positional)
+}
+
+// splitCommand returns the subcommand named by the first positional
+// argument, or get when it names none, and the remaining arguments
+func splitCommand(positional []string) (string, []string) {
+	if len(positional) > 0 {
+		if _, ok := commands[positional[0]]; ok {
+			return positional[0], positional[1:]
+		}
+	}
+	return "get", positional
+}
+
+// parseArgs parses flags that may appear anywhere on the command line and
+// returns the remaining positional arguments
+func (e *env) parseArgs(args []string) ([]string, error) {
+	fs := flag.NewFlagSet("go-getter-file", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	fs.IntVar(&e.opts.parallelism, "parallelism", 0, "")
+	fs.IntVar(&e.opts.maxFetches, "max-concurrency", 0, "")
+	fs.IntVar(&e.opts.retries, "retries", 0, "")
+	fs.DurationVar(&e.opts.timeout, "timeout", 0, "")
+	fs.BoolVar(&e.opts.quiet, "quiet", false, "")
+	fs.BoolVar(&e.opts.quiet, "q", false, "")
+	fs.StringVar(&e.opts.output, "output", outputText, "")
+	fs.StringVar(&e.opts.output, "o", outputText, "")
+	fs.Var(&e.opts.vars, "var", "")
+	fs.StringVar(&e.opts.cacheDir, "cache-dir", "", "")
+	fs.StringVar(&e.opts.policy, "policy", "", "")
+	fs.StringVar(&e.opts.outputRoot, "output-root", "", "")
+	fs.StringVar(&e.opts.conflicts, "dest-conflicts", processor.DestConflictsError, "")
+	fs.DurationVar(&e.opts.olderThan, "older-than", 0, "")
+	fs.StringVar(&e.opts.report, "report", "", "")
+	fs.StringVar(&e.opts.logLevel, "log-level", "info", "")
+	fs.StringVar(&e.opts.logFormat, "log-format", outputText, "")
+	fs.StringVar(&e.opts.logFile, "log-file", "", "")
+	fs.BoolVar(&e.opts.destFromCwd, "dest-relative-to-cwd", false, "")
+	fs.BoolVar(&e.opts.locked, "locked", false, "")
+	fs.BoolVar(&e.opts.dryRun, "dry-run", false, "")
+	fs.BoolVar(&e.opts.help, "help", false, "")
+	fs.BoolVar(&e.opts.help, "h", false, "")
+	fs.BoolVar(&e.opts.version, "version", false, "")
+	fs.BoolVar(&e.opts.version, "v", false, "")
+
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+
+	if e.opts.output != outputText && e.opts.output != outputJSON {
+		return nil, fmt.Errorf("invalid output format %q, expected %s or %s", e.opts.output, outputText, outputJSON)
+	}
+	if e.opts.logFormat != outputText && e.opts.logFormat != outputJSON {
+		return nil, fmt.Errorf("invalid log format %q, expected %s or %s", e.opts.logFormat, outputText, outputJSON)
+	}
+	if e.opts.conflicts != processor.DestConflictsError && e.opts.conflicts != processor.DestConflictsWarn {
+		return nil, fmt.Errorf("invalid dest conflict handling %q, expected %s or %s",
+			e.opts.conflicts, processor.DestConflictsError, processor.DestConflictsWarn)
+	}
+	if err := e.level.UnmarshalText([]byte(e.opts.logLevel)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q, expected debug, info, warn or error", e.opts.logLevel)
+	}
+	if e.opts.quiet && !isFlagSet(fs, "log-level") {
+		e.level = slog.LevelError
+	}
+
+	var errs []error
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "parallelism":
+			if e.opts.parallelism < 1 {
+				errs = append(errs, fmt.Errorf("--parallelism must be at least 1"))
+			}
+			e.overrides.Parallelism = &e.opts.parallelism
+		case "max-concurrency":
+			if e.opts.maxFetches < 1 {
+				errs = append(errs, fmt.Errorf("--max-concurrency must be at least 1"))
+			}
+		case "retries":
+			if e.opts.retries < 0 {
+				errs = append(errs, fmt.Errorf("--retries must not be negative"))
+			}
+			e.overrides.Retries = &e.opts.retries
+		case "timeout":
+			if e.opts.timeout <= 0 {
+				errs = append(errs, fmt.Errorf("--timeout must be positive"))
+			}
+			e.overrides.Timeout = &e.opts.timeout
+		case "cache-dir":
+			e.overrides.CacheDir = &e.opts.cacheDir
+		case "output-root":
+			root, err := filepath.Abs(e.opts.outputRoot)
+			if err != nil || e.opts.outputRoot == "" {
+				errs = append(errs, fmt.Errorf("--output-root must be a directory"))
+			}
+			e.opts.outputRoot = root
+			e.overrides.OutputRoot = &e.opts.outputRoot
+		}
+	})
+
+	return positional, errors.Join(errs...)
+}
+
+// isFlagSet reports whether the flag name was given on the command line
+func isFlagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// setupLogging creates the logger of the run. Logs go to --log-file, or to
+// stdout unless json output is requested. The returned function closes the
+// log file.
+func (e *env) setupLogging() (func(), error) {
+	closeLog := func() {}
+	switch {
+	case e.opts.logFile != "":
+		f, err := os.OpenFile(e.opts.logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open log file %s: %w", e.opts.logFile, err)
+		}
+		e.logOut = f
+		closeLog = func() { f.Close() }
+	case e.opts.output == outputJSON:
+		e.logOut = io.Discard
+	default:
+		e.logOut = e.stdout
+	}
+
+	e.logger = e.newLogger(e.logOut)
+	return closeLog, nil
+}
+
+// newLogger creates a logger writing to w with the configured level and format
+func (e *env) newLogger(w io.Writer) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: e.level}
+	if e.opts.logFormat == outputJSON {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+// newProcessor creates a processor for the given paths honouring the flags
+func (e *env) newProcessor(paths []string, opts ...processor.Option) (*processor.Processor, error) {
+	policy, err := e.loadPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	defaults := []processor.Option{
+		processor.WithMaxConcurrency(e.opts.maxFetches),
+		processor.WithOverrides(e.overrides),
+		processor.WithVars(e.opts.vars),
+		processor.WithPolicy(policy),
+		processor.WithDestConflicts(e.opts.conflicts),
+		processor.WithLogger(e.logger),
+	}
+	if e.opts.destFromCwd {
+		defaults = append(defaults, processor.WithDestFromCwd())
+	}
+	// Options of the caller come last so they take precedence
+	return processor.New(paths, append(defaults, opts...)...)
+}
+
+// loadPolicy loads the global policy file given with --policy or
+// GO_GETTER_FILE_POLICY, or returns nil when there is none
+func (e *env) loadPolicy() (*config.Policy, error) {
+	path := e.opts.policy
+	if path == "" {
+		path = os.Getenv(config.PolicyEnv)
+	}
+	if path == "" {
+		return nil, nil
+	}
+	return config.LoadPolicy(path)
+}
+
+// isTerminal reports whether w is a terminal
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// printf prints informational messages unless quiet or json output is requested
+func (e *env) printf(format string, args ...any) {
+	if e.opts.quiet || e.opts.output == outputJSON {
+		return
+	}
+	fmt.Fprintf(e.stdout, format, args...)
 }
 
 func printUsage(w io.Writer, version string) {
@@ -46,15 +346,59 @@ func printUsage(w io.Writer, version string) {
 CLI application for fetching files using go-getter with YAML configuration.
 
 Usage:
-  go-getter-file [options] <config-file-or-directory>...
+  go-getter-file [options] [command] <config-file-or-directory>...
+
+Commands:
+  get            Fetch all sources (default when no command is given)
+  lock           Fetch sources and write a *.go.getter.lock next to each config
+  plan           Show which destinations are missing, unchanged or would be
+                 overwritten without downloading anything
+  validate       Load and validate configuration files without fetching and
+                 report every problem with its position
+  schema         Print the JSON Schema of configuration files
+  migrate        Rewrite version 1 configuration files to version 2 in place,
+                 keeping comments (print them instead with --dry-run)
+  list           List configuration files and their sources
+  clean          Remove the destinations of all sources, requires an output
+                 root
+  cache <action> Manage the download cache: list, prune or verify
+  version        Show version information
+  help           Show this help message
 
 Options:
-  -h, --help     Show this help message
-  -v, --version  Show version information
+  -h, --help               Show this help message
+  -v, --version            Show version information
+  --parallelism <n>        Override the number of parallel fetches per config
+  --max-concurrency <n>    Limit the number of fetches running at once across
+                           all configs (default: no limit)
+  --retries <n>            Override the number of retries per source
+  --timeout <duration>     Override the timeout per fetch (e.g. 30s, 2m)
+  -q, --quiet              Only print errors and command results
+  --log-level <level>      Log level: debug, info, warn or error (default: info)
+  --log-format <format>    Log format: text or json (default: text)
+  --log-file <file>        Append logs to file instead of stdout
+  -o, --output <format>    Output format: text or json (default: text)
+  --var <key=value>        Set a configuration variable, may be repeated
+  --cache-dir <dir>        Cache downloads in dir (default: $GO_GETTER_FILE_CACHE_DIR)
+  --policy <file>          Only allow sources permitted by the policy file
+                           (default: $GO_GETTER_FILE_POLICY)
+  --output-root <dir>      Reject dest paths outside dir and downloads with
+                           symlinks or special files escaping them
+  --dest-conflicts <mode>  Sources with the same or nested dest paths fail the
+                           run before fetching (error) or are only logged (warn)
+                           (default: error)
+  --older-than <duration>  With cache prune, only remove entries unused for duration
+  --report <file>          With get and lock, write a json run report to file
+  --dest-relative-to-cwd   Resolve relative dest paths against the working
+                           directory instead of the config file directory
+  --locked                 Fail if fetched sources differ from the *.go.getter.lock files
+  --dry-run                Same as the plan command for get and lock; with
+                           migrate, print the migrated files instead of
+                           writing them
 
 Arguments:
-  One or more configuration files (*.go.getter.yaml) or directories.
-  Directories will be scanned for *.go.getter.yaml files.
+  One or more configuration files (*.go.getter.yaml, .json, .toml or .hcl)
+  or directories. Directories will be scanned for files of every format.
 
 Examples:
   # Process a single configuration file
@@ -69,8 +413,38 @@ Examples:
   # Mix files and directories
   go-getter-file project1.go.getter.yaml configs/
 
+  # Override configuration values for a CI run
+  go-getter-file get --retries 5 --timeout 2m configs/
+
+  # Set variables referenced as ${version} in url and dest
+  go-getter-file --var version=v1.2.0 configs/
+
+  # Record the fetched sources and enforce them on later runs
+  go-getter-file lock configs/
+  go-getter-file --locked configs/
+
+  # Review what a sync would change
+  go-getter-file plan configs/
+
+  # Share downloads between projects and prune unused entries
+  go-getter-file --cache-dir ~/.cache/go-getter-file configs/
+  go-getter-file cache prune --cache-dir ~/.cache/go-getter-file --older-than 720h
+
+  # Write a json report of every fetch for dashboards
+  go-getter-file get --report report.json configs/
+
+  # Validate configuration files and list their sources as JSON
+  go-getter-file validate configs/
+  go-getter-file schema > go-getter-file.schema.json
+
+  # Upgrade configuration files to the version 2 format
+  go-getter-file migrate --dry-run configs/
+  go-getter-file migrate configs/
+  go-getter-file list --output json configs/
+
 Configuration:
-  Configuration files are in YAML format (*.go.getter.yaml)
+  Configuration files are in YAML (*.go.getter.yaml), JSON (*.go.getter.json),
+  TOML (*.go.getter.toml) or HCL (*.go.getter.hcl) format
   See README.md for configuration file format and examples.
 
 `, version)
@@ -0,0 +1,195 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/universal-development/go-getter-file/internal/config"
+)
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		want        []string
+		wantCommand string
+		check       func(opts options) bool
+		wantErr     string
+	}{
+		{
+			name:        "paths only",
+			args:        []string{"configs", "more"},
+			want:        []string{"configs", "more"},
+			wantCommand: "get",
+		},
+		{
+			name:        "subcommand",
+			args:        []string{"validate", "configs"},
+			want:        []string{"configs"},
+			wantCommand: "validate",
+		},
+		{
+			name:        "flags mixed with positional arguments",
+			args:        []string{"-q", "lock", "configs", "--dry-run", "more", "--output", "json"},
+			want:        []string{"configs", "more"},
+			wantCommand: "lock",
+			check: func(opts options) bool {
+				return opts.quiet && opts.dryRun && opts.output == outputJSON
+			},
+		},
+		{
+			name:        "flags after the last path",
+			args:        []string{"clean", "configs", "--output-root=out", "-o=json"},
+			want:        []string{"configs"},
+			wantCommand: "clean",
+			check: func(opts options) bool {
+				return filepath.IsAbs(opts.outputRoot) && opts.output == outputJSON
+			},
+		},
+		{
+			name:        "repeated vars",
+			args:        []string{"--var", "a=1", "configs", "--var=b=2"},
+			want:        []string{"configs"},
+			wantCommand: "get",
+			check: func(opts options) bool {
+				return reflect.DeepEqual(map[string]string(opts.vars), map[string]string{"a": "1", "b": "2"})
+			},
+		},
+		{
+			name:        "cache action stays positional",
+			args:        []string{"cache", "--older-than", "1h", "prune"},
+			want:        []string{"cache", "prune"},
+			wantCommand: "get",
+			check: func(opts options) bool {
+				return opts.olderThan == time.Hour
+			},
+		},
+		{name: "unknown flag", args: []string{"configs", "--nope"}, wantErr: "flag provided but not defined"},
+		{name: "invalid output", args: []string{"-o", "yaml", "configs"}, wantErr: "invalid output format"},
+		{name: "invalid var", args: []string{"--var", "a", "configs"}, wantErr: "expected key=value"},
+		{name: "invalid parallelism", args: []string{"--parallelism", "0", "configs"}, wantErr: "--parallelism must be at least 1"},
+		{name: "negative retries", args: []string{"configs", "--retries", "-1"}, wantErr: "--retries must not be negative"},
+		{name: "invalid log level", args: []string{"--log-level", "loud", "configs"}, wantErr: "invalid log level"},
+		{name: "invalid dest conflicts", args: []string{"--dest-conflicts", "ignore", "configs"}, wantErr: "invalid dest conflict handling"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &env{}
+			positional, err := e.parseArgs(tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseArgs() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseArgs() unexpected error: %v", err)
+			}
+
+			command, paths := splitCommand(positional)
+			if command != tt.wantCommand || !reflect.DeepEqual(paths, tt.want) {
+				t.Errorf("splitCommand() = %q, %q, want %q, %q", command, paths, tt.wantCommand, tt.want)
+			}
+			if tt.check != nil && !tt.check(e.opts) {
+				t.Errorf("parseArgs() options = %+v, not as expected", e.opts)
+			}
+		})
+	}
+}
+
+func TestParseArgsOverrides(t *testing.T) {
+	root, err := filepath.Abs("out")
+	if err != nil {
+		t.Fatalf("filepath.Abs() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want config.Config
+	}{
+		{
+			name: "no flags",
+			args: []string{"configs"},
+			want: config.Config{},
+		},
+		{
+			name: "flags without overrides",
+			args: []string{"configs", "--max-concurrency", "4", "-q", "--dry-run"},
+			want: config.Config{},
+		},
+		{
+			name: "all overrides",
+			args: []string{"--parallelism", "3", "configs", "--retries", "0", "--timeout", "1m", "--cache-dir", "/cache", "--output-root", "out"},
+			want: config.Config{Parallelism: 3, Timeout: time.Minute, CacheDir: "/cache", OutputRoot: root},
+		},
+		{
+			name: "zero retries override",
+			args: []string{"--retries=0", "configs"},
+			want: config.Config{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &env{}
+			if _, err := e.parseArgs(tt.args); err != nil {
+				t.Fatalf("parseArgs() unexpected error: %v", err)
+			}
+
+			// Overrides are applied over values that differ from every
+			// expected result, so untouched fields show up
+			got := config.Config{Retries: 7}
+			e.overrides.Apply(&got)
+			want := tt.want
+			if e.overrides.Retries == nil {
+				want.Retries = 7
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Apply() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestRunDispatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr string
+	}{
+		{name: "help", args: []string{"help"}, want: "Usage:"},
+		{name: "help flag after path", args: []string{"configs", "--help"}, want: "Usage:"},
+		{name: "version", args: []string{"version"}, want: "go-getter-file version 1.2.3"},
+		{name: "version json", args: []string{"-o", "json", "--version"}, want: `"version": "1.2.3"`},
+		{name: "no paths", args: []string{}, wantErr: "no configuration files or directories specified"},
+		{name: "subcommand without paths", args: []string{"-q", "validate"}, wantErr: "validate: no configuration files or directories specified"},
+		{name: "cache without action", args: []string{"cache", "--cache-dir", "/tmp"}, wantErr: "expected exactly one action"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout bytes.Buffer
+			err := Run(context.Background(), "1.2.3", tt.args, &stdout)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Run() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run() unexpected error: %v", err)
+			}
+			if !strings.Contains(stdout.String(), tt.want) {
+				t.Errorf("Run() output = %q, want it to contain %q", stdout.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package app
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/universal-development/go-getter-file/internal/cache"
+	"github.com/universal-development/go-getter-file/internal/config"
+)
+
+// cacheResult is the json representation of a cache entry
+type cacheResult struct {
+	cache.Entry
+	Error string `json:"error,omitempty"`
+}
+
+// runCache lists, prunes or verifies the entries of the download cache
+func runCache(e *env, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("cache: expected exactly one action: list, prune or verify")
+	}
+
+	dir := e.opts.cacheDir
+	if dir == "" {
+		dir = os.Getenv(config.CacheDirEnv)
+	}
+	if dir == "" {
+		return fmt.Errorf("cache: no cache directory, use --cache-dir or set %s", config.CacheDirEnv)
+	}
+	c := cache.New(dir)
+
+	switch args[0] {
+	case "list":
+		entries, err := c.List()
+		if err != nil {
+			return err
+		}
+		return e.writeCacheEntries(toCacheResults(entries), "")
+
+	case "prune":
+		removed, err := c.Prune(e.opts.olderThan)
+		if writeErr := e.writeCacheEntries(toCacheResults(removed), "Removed "); writeErr != nil {
+			return writeErr
+		}
+		e.printf("Pruned %d cache entries from %s\n", len(removed), dir)
+		return err
+
+	case "verify":
+		verified, err := c.Verify()
+		if err != nil {
+			return err
+		}
+		results := make([]cacheResult, 0, len(verified))
+		var failed int
+		for _, v := range verified {
+			result := cacheResult{Entry: v.Entry}
+			if v.Err != nil {
+				failed++
+				result.Error = v.Err.Error()
+			}
+			results = append(results, result)
+		}
+		if err := e.writeCacheEntries(results, ""); err != nil {
+			return err
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d cache entries failed verification", failed, len(results))
+		}
+		return nil
+	}
+
+	return fmt.Errorf("cache: unknown action %q, expected list, prune or verify", args[0])
+}
+
+func toCacheResults(entries []cache.Entry) []cacheResult {
+	results := make([]cacheResult, 0, len(entries))
+	for _, entry := range entries {
+		results = append(results, cacheResult{Entry: entry})
+	}
+	return results
+}
+
+// writeCacheEntries prints cache entries in the requested output format
+func (e *env) writeCacheEntries(results []cacheResult, prefix string) error {
+	if e.opts.output == outputJSON {
+		return e.writeJSON(results)
+	}
+
+	for _, r := range results {
+		status := ""
+		if r.Error != "" {
+			status = "  FAIL: " + r.Error
+		}
+		fmt.Fprintf(e.stdout, "%s%s  %s  %d bytes  last used %s%s\n",
+			prefix, shortKey(r.Key), r.URL, r.Size, r.LastUsed.Format("2006-01-02 15:04"), status)
+	}
+	return nil
+}
+
+// shortKey returns the prefix of a cache key shown in listings
+func shortKey(key string) string {
+	if len(key) > 12 {
+		return key[:12]
+	}
+	return key
+}
@@ -0,0 +1,376 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/universal-development/go-getter-file/internal/config"
+	"github.com/universal-development/go-getter-file/internal/processor"
+	"github.com/universal-development/go-getter-file/internal/progress"
+)
+
+// runGet fetches all sources of the given configuration files
+func runGet(ctx context.Context, e *env, paths []string) error {
+	if e.opts.dryRun {
+		return runPlan(ctx, e, paths)
+	}
+	var opts []processor.Option
+	if e.opts.locked {
+		opts = append(opts, processor.WithLocked())
+	}
+
+	return e.process(ctx, paths, opts...)
+}
+
+// runLock fetches all sources and writes a lockfile next to every config.
+// With --dry-run nothing is fetched or written and the plan is shown.
+func runLock(ctx context.Context, e *env, paths []string) error {
+	if e.opts.dryRun {
+		return runPlan(ctx, e, paths)
+	}
+	return e.process(ctx, paths, processor.WithWriteLock())
+}
+
+// process runs the processor for the fetching commands and writes the run
+// report to --report and, with json output, to stdout
+func (e *env) process(ctx context.Context, paths []string, opts ...processor.Option) error {
+	e.printf("go-getter-file version %s\n", e.version)
+
+	// Progress is rendered as bars on a terminal and logged periodically
+	// otherwise. Logs sharing the terminal go through the reporter so they
+	// do not tear the bars.
+	if !e.opts.quiet && e.opts.output != outputJSON {
+		var reporter *progress.Reporter
+		if isTerminal(e.stdout) {
+			reporter = progress.New(e.stdout, true)
+			if e.logOut == e.stdout {
+				opts = append(opts, processor.WithLogger(e.newLogger(reporter)))
+			}
+		} else {
+			reporter = progress.New(e.stdout, false, progress.WithLogger(e.logger))
+		}
+		defer reporter.Close()
+		opts = append(opts, processor.WithProgress(reporter))
+	}
+
+	proc, err := e.newProcessor(paths, opts...)
+	if err != nil {
+		return err
+	}
+
+	report, err := proc.Process(ctx)
+	if e.opts.report != "" {
+		if reportErr := writeReportFile(e.opts.report, report); reportErr != nil {
+			return errors.Join(err, reportErr)
+		}
+	}
+	if e.opts.output == outputJSON {
+		if encErr := processor.WriteReport(e.stdout, report); encErr != nil {
+			return errors.Join(err, encErr)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	e.printf("\nAll configuration files processed successfully!\n")
+	return nil
+}
+
+// writeReportFile writes the run report to path
+func writeReportFile(path string, report *processor.Report) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create report %s: %w", path, err)
+	}
+	if err := processor.WriteReport(f, report); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write report %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// runPlan reports what fetching would change without downloading anything
+func runPlan(_ context.Context, e *env, paths []string) error {
+	proc, err := e.newProcessor(paths)
+	if err != nil {
+		return err
+	}
+
+	plans, err := proc.Plan()
+	if e.opts.output == outputJSON {
+		if encErr := e.writeJSON(plans); encErr != nil {
+			return encErr
+		}
+		return err
+	}
+
+	processor.WritePlan(e.stdout, plans)
+	return err
+}
+
+// validateResult is the json representation of a validated config
+type validateResult struct {
+	Path     string            `json:"path"`
+	Name     string            `json:"name,omitempty"`
+	Valid    bool              `json:"valid"`
+	Error    string            `json:"error,omitempty"`
+	Problems []validateProblem `json:"problems,omitempty"`
+}
+
+// validateProblem is the json representation of a single problem of a
+// config, with its position when known
+type validateProblem struct {
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// toValidateProblems returns the positioned problems of a validation error
+func toValidateProblems(err error) []validateProblem {
+	var validationErr *config.ValidationError
+	if !errors.As(err, &validationErr) {
+		return nil
+	}
+	problems := make([]validateProblem, len(validationErr.Errors))
+	for i, fieldErr := range validationErr.Errors {
+		problems[i] = validateProblem{
+			File:    fieldErr.File,
+			Line:    fieldErr.Line,
+			Column:  fieldErr.Column,
+			Message: fieldErr.Err.Error(),
+		}
+	}
+	return problems
+}
+
+// runValidate loads and validates configuration files without fetching
+func runValidate(_ context.Context, e *env, paths []string) error {
+	proc, err := e.newProcessor(paths)
+	if err != nil {
+		return err
+	}
+
+	var results []validateResult
+	var failed int
+	for _, loaded := range proc.Load() {
+		result := validateResult{Path: loaded.Path, Valid: loaded.Err == nil}
+		if loaded.Err != nil {
+			failed++
+			result.Error = loaded.Err.Error()
+			result.Problems = toValidateProblems(loaded.Err)
+		} else {
+			result.Name = loaded.Config.Name
+		}
+		results = append(results, result)
+	}
+
+	if e.opts.output == outputJSON {
+		if err := e.writeJSON(results); err != nil {
+			return err
+		}
+	} else {
+		for _, result := range results {
+			if result.Valid {
+				fmt.Fprintf(e.stdout, "OK    %s (%s)\n", result.Path, result.Name)
+			} else {
+				fmt.Fprintf(e.stdout, "FAIL  %s: %s\n", result.Path, result.Error)
+			}
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d configuration file(s) failed validation", failed, len(results))
+	}
+	return nil
+}
+
+// migrateResult is the json representation of a migrated config
+type migrateResult struct {
+	Path     string `json:"path"`
+	Migrated bool   `json:"migrated"`
+	Error    string `json:"error,omitempty"`
+}
+
+// runMigrate rewrites configuration files to the latest format version in
+// place, keeping their comments. With --dry-run the migrated files are
+// printed instead.
+func runMigrate(_ context.Context, e *env, paths []string) error {
+	proc, err := processor.New(paths, processor.WithLogger(e.logger))
+	if err != nil {
+		return err
+	}
+
+	var results []migrateResult
+	var failed int
+	for _, path := range proc.ConfigFiles() {
+		result := migrateResult{Path: path}
+		migrated, err := migrateFile(path, e.opts.dryRun)
+		switch {
+		case errors.Is(err, config.ErrAlreadyMigrated):
+		case err != nil:
+			failed++
+			result.Error = err.Error()
+		default:
+			result.Migrated = true
+		}
+		results = append(results, result)
+
+		if e.opts.dryRun && migrated != nil && e.opts.output != outputJSON {
+			fmt.Fprintf(e.stdout, "# %s\n%s", path, migrated)
+		}
+	}
+
+	if e.opts.output == outputJSON {
+		if err := e.writeJSON(results); err != nil {
+			return err
+		}
+	} else if !e.opts.dryRun {
+		for _, result := range results {
+			switch {
+			case result.Error != "":
+				fmt.Fprintf(e.stdout, "FAIL  %s: %s\n", result.Path, result.Error)
+			case result.Migrated:
+				fmt.Fprintf(e.stdout, "OK    %s (version %d)\n", result.Path, config.LatestVersion)
+			default:
+				fmt.Fprintf(e.stdout, "SKIP  %s (already version %d)\n", result.Path, config.LatestVersion)
+			}
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d configuration file(s) failed to migrate", failed, len(results))
+	}
+	return nil
+}
+
+// migrateFile migrates the configuration file at path and writes it back
+// unless dryRun is set. It returns the migrated content.
+func migrateFile(path string, dryRun bool) ([]byte, error) {
+	if format := config.FormatFor(path); format.Name != "yaml" {
+		return nil, fmt.Errorf("migrate only supports YAML files, not %s", format.Name)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	migrated, err := config.Migrate(data)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun {
+		return migrated, nil
+	}
+	if err := os.WriteFile(path, migrated, info.Mode().Perm()); err != nil {
+		return nil, err
+	}
+	return migrated, nil
+}
+
+// runSchema prints the JSON Schema of configuration files
+func runSchema(e *env, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("schema: unexpected arguments %v", args)
+	}
+	return e.writeJSON(config.JSONSchema())
+}
+
+// listConfig is the json representation of a listed config
+type listConfig struct {
+	Path    string       `json:"path"`
+	Name    string       `json:"name"`
+	Sources []listSource `json:"sources"`
+}
+
+// listSource is the json representation of a listed source
+type listSource struct {
+	Name string `json:"name,omitempty"`
+	URL  string `json:"url"`
+	Dest string `json:"dest"`
+}
+
+// runList lists configuration files and their sources
+func runList(_ context.Context, e *env, paths []string) error {
+	proc, err := e.newProcessor(paths)
+	if err != nil {
+		return err
+	}
+
+	var configs []listConfig
+	for _, loaded := range proc.Load() {
+		if loaded.Err != nil {
+			return loaded.Err
+		}
+		cfg := listConfig{Path: loaded.Path, Name: loaded.Config.Name}
+		for _, src := range loaded.Config.Sources {
+			cfg.Sources = append(cfg.Sources, listSource{Name: src.Name, URL: src.URL, Dest: src.Dest})
+		}
+		configs = append(configs, cfg)
+	}
+
+	if e.opts.output == outputJSON {
+		return e.writeJSON(configs)
+	}
+
+	for _, cfg := range configs {
+		fmt.Fprintf(e.stdout, "%s (%s)\n", cfg.Path, cfg.Name)
+		for _, src := range cfg.Sources {
+			if src.Name != "" {
+				fmt.Fprintf(e.stdout, "  %s: %s -> %s\n", src.Name, src.URL, src.Dest)
+				continue
+			}
+			fmt.Fprintf(e.stdout, "  %s -> %s\n", src.URL, src.Dest)
+		}
+	}
+	return nil
+}
+
+// runClean removes the destinations of all sources
+func runClean(_ context.Context, e *env, paths []string) error {
+	proc, err := e.newProcessor(paths)
+	if err != nil {
+		return err
+	}
+
+	removed, err := proc.Clean()
+	if e.opts.output == outputJSON {
+		if removed == nil {
+			removed = []string{}
+		}
+		if encErr := e.writeJSON(map[string][]string{"removed": removed}); encErr != nil {
+			return encErr
+		}
+		return err
+	}
+
+	for _, path := range removed {
+		fmt.Fprintf(e.stdout, "Removed %s\n", path)
+	}
+	e.printf("Cleaned %d destination(s)\n", len(removed))
+	return err
+}
+
+// runVersion prints version information
+func runVersion(e *env) error {
+	if e.opts.output == outputJSON {
+		return e.writeJSON(map[string]string{"version": e.version})
+	}
+
+	fmt.Fprintf(e.stdout, "go-getter-file version %s\n", e.version)
+	return nil
+}
+
+// writeJSON writes v as indented json to stdout
+func (e *env) writeJSON(v any) error {
+	enc := json.NewEncoder(e.stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,353 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/universal-development/go-getter-file/internal/checksum"
+)
+
+const (
+	dataDir  = "data"
+	metaFile = "meta.json"
+)
+
+// Cache is a content-addressed store of downloaded sources
+type Cache struct {
+	dir string
+}
+
+// Entry describes a cached download
+type Entry struct {
+	Key      string    `json:"key"`
+	URL      string    `json:"url"`
+	Ref      string    `json:"ref,omitempty"`
+	Checksum string    `json:"checksum,omitempty"`
+	Hash     string    `json:"hash"`
+	Size     int64     `json:"size"`
+	Created  time.Time `json:"created"`
+	LastUsed time.Time `json:"last-used"`
+}
+
+// VerifyResult is the outcome of verifying a single cache entry
+type VerifyResult struct {
+	Entry Entry
+	Err   error
+}
+
+// New returns a cache rooted at dir. The directory is created on first store.
+func New(dir string) *Cache {
+	return &Cache{dir: dir}
+}
+
+// Dir returns the root directory of the cache
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Key returns the cache key for a resolved URL and an optional checksum.
+// The URL is normalized by sorting its query parameters and the git ref is
+// keyed separately so equivalent spellings share an entry.
+func Key(resolvedURL, sum string) (key, normalized, ref string) {
+	normalized = resolvedURL
+	forced, rawURL, ok := strings.Cut(resolvedURL, "::")
+	if !ok {
+		forced, rawURL = "", resolvedURL
+	}
+
+	if u, err := url.Parse(rawURL); err == nil {
+		q := u.Query()
+		ref = q.Get("ref")
+		q.Del("ref")
+		u.RawQuery = q.Encode()
+		normalized = u.String()
+		if forced != "" {
+			normalized = forced + "::" + normalized
+		}
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n", normalized, ref, sum)
+	return hex.EncodeToString(h.Sum(nil)), normalized, ref
+}
+
+// Lookup returns the entry stored under key
+func (c *Cache) Lookup(key string) (*Entry, bool) {
+	entry, err := c.readEntry(key)
+	if err != nil {
+		return nil, false
+	}
+	if _, err := os.Lstat(filepath.Join(c.dir, key, dataDir)); err != nil {
+		return nil, false
+	}
+	return entry, true
+}
+
+// Restore copies the content stored under key to dst and records the use
+// of the entry. The content is copied rather than linked so that editing
+// dst cannot change the cache.
+func (c *Cache) Restore(key, dst string) error {
+	entry, ok := c.Lookup(key)
+	if !ok {
+		return fmt.Errorf("cache entry %s not found", key)
+	}
+
+	if err := copyTree(filepath.Join(c.dir, key, dataDir), dst); err != nil {
+		return fmt.Errorf("failed to restore cache entry %s: %w", key, err)
+	}
+
+	entry.LastUsed = time.Now()
+	return c.writeEntry(entry)
+}
+
+// Store copies src into the cache under the key of entry
+func (c *Cache) Store(entry Entry, src string) error {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory %s: %w", c.dir, err)
+	}
+
+	tmp, err := os.MkdirTemp(c.dir, ".tmp-")
+	if err != nil {
+		return fmt.Errorf("failed to create cache staging directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := copyTree(src, filepath.Join(tmp, dataDir)); err != nil {
+		return fmt.Errorf("failed to copy %s into cache: %w", src, err)
+	}
+
+	data := filepath.Join(tmp, dataDir)
+	hash, err := checksum.Sum(checksum.SHA256, data)
+	if err != nil {
+		return err
+	}
+	size, err := treeSize(data)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	entry.Hash = checksum.SHA256 + ":" + hash
+	entry.Size = size
+	entry.Created = now
+	entry.LastUsed = now
+
+	if err := writeJSON(filepath.Join(tmp, metaFile), entry); err != nil {
+		return err
+	}
+
+	target := filepath.Join(c.dir, entry.Key)
+	if err := os.RemoveAll(target); err != nil {
+		return err
+	}
+	return os.Rename(tmp, target)
+}
+
+// Remove deletes the entry stored under key
+func (c *Cache) Remove(key string) error {
+	return os.RemoveAll(filepath.Join(c.dir, key))
+}
+
+// List returns all cache entries sorted by URL. Directories that are not
+// named like a key and entries with unreadable or invalid metadata are
+// skipped.
+func (c *Cache) List() ([]Entry, error) {
+	dirs, err := os.ReadDir(c.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache directory %s: %w", c.dir, err)
+	}
+
+	var entries []Entry
+	for _, d := range dirs {
+		if !d.IsDir() || !validKey(d.Name()) {
+			continue
+		}
+		entry, err := c.readEntry(d.Name())
+		if err != nil {
+			continue
+		}
+		entries = append(entries, *entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].URL != entries[j].URL {
+			return entries[i].URL < entries[j].URL
+		}
+		return entries[i].Key < entries[j].Key
+	})
+	return entries, nil
+}
+
+// Prune removes entries that were not used within maxAge, or all entries
+// when maxAge is zero, and returns the removed entries
+func (c *Cache) Prune(maxAge time.Duration) ([]Entry, error) {
+	entries, err := c.List()
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+	var removed []Entry
+	for _, entry := range entries {
+		if maxAge > 0 && entry.LastUsed.After(cutoff) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(c.dir, entry.Key)); err != nil {
+			return removed, fmt.Errorf("failed to remove cache entry %s: %w", entry.Key, err)
+		}
+		removed = append(removed, entry)
+	}
+
+	return removed, nil
+}
+
+// Verify recomputes the hash of every entry and compares it with the hash
+// recorded when the entry was stored
+func (c *Cache) Verify() ([]VerifyResult, error) {
+	entries, err := c.List()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]VerifyResult, 0, len(entries))
+	for _, entry := range entries {
+		result := VerifyResult{Entry: entry}
+		hash, err := checksum.Sum(checksum.SHA256, filepath.Join(c.dir, entry.Key, dataDir))
+		switch {
+		case err != nil:
+			result.Err = err
+		case checksum.SHA256+":"+hash != entry.Hash:
+			result.Err = &checksum.MismatchError{
+				Path:     filepath.Join(c.dir, entry.Key),
+				Expected: entry.Hash,
+				Actual:   checksum.SHA256 + ":" + hash,
+			}
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// validKey reports whether key has the form of the keys returned by Key
+func validKey(key string) bool {
+	_, err := hex.DecodeString(key)
+	return err == nil && len(key) == 2*sha256.Size
+}
+
+// readEntry reads the metadata of a cache entry. Entries recording another
+// key are invalid, removing them by their key would remove something else.
+func (c *Cache) readEntry(key string) (*Entry, error) {
+	data, err := os.ReadFile(filepath.Join(c.dir, key, metaFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("failed to parse cache entry %s: %w", key, err)
+	}
+	if entry.Key != key {
+		return nil, fmt.Errorf("cache entry %s records key %q", key, entry.Key)
+	}
+	return &entry, nil
+}
+
+// writeEntry updates the metadata of a cache entry
+func (c *Cache) writeEntry(entry *Entry) error {
+	return writeJSON(filepath.Join(c.dir, entry.Key, metaFile), entry)
+}
+
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// copyTree copies a file or directory tree
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case d.Type()&fs.ModeSymlink != 0:
+			linkTarget, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(linkTarget, target)
+		case d.Type().IsRegular():
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			return copyFile(path, target, info.Mode().Perm())
+		}
+		return nil
+	})
+}
+
+func copyFile(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// treeSize returns the total size of the regular files under path
+func treeSize(path string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, err
+}
@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestKey(t *testing.T) {
+	keyA, normalized, ref := Key("git::https://github.com/org/repo.git?ref=v1.0.0&depth=1", "")
+	keyB, _, _ := Key("git::https://github.com/org/repo.git?depth=1&ref=v1.0.0", "")
+	if keyA != keyB {
+		t.Errorf("Key() differs for equivalent URLs: %s != %s", keyA, keyB)
+	}
+	if normalized != "git::https://github.com/org/repo.git?depth=1" {
+		t.Errorf("normalized = %s, want git::https://github.com/org/repo.git?depth=1", normalized)
+	}
+	if ref != "v1.0.0" {
+		t.Errorf("ref = %s, want v1.0.0", ref)
+	}
+
+	keyC, _, _ := Key("git::https://github.com/org/repo.git?ref=v2.0.0", "")
+	if keyA == keyC {
+		t.Error("Key() should differ for different refs")
+	}
+
+	keyD, _, _ := Key("git::https://github.com/org/repo.git?ref=v1.0.0&depth=1", "sha256:abcd")
+	if keyA == keyD {
+		t.Error("Key() should differ for different checksums")
+	}
+}
+
+func TestStoreRestore(t *testing.T) {
+	c := New(filepath.Join(t.TempDir(), "cache"))
+
+	src := filepath.Join(t.TempDir(), "src")
+	if err := os.MkdirAll(filepath.Join(src, "sub"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "sub", "file.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	key, url, _ := Key("http::https://example.com/file.txt", "")
+	if _, ok := c.Lookup(key); ok {
+		t.Fatal("Lookup() found entry in empty cache")
+	}
+
+	if err := c.Store(Entry{Key: key, URL: url}, src); err != nil {
+		t.Fatalf("Store() unexpected error: %v", err)
+	}
+
+	entry, ok := c.Lookup(key)
+	if !ok {
+		t.Fatal("Lookup() did not find stored entry")
+	}
+	if entry.Size != 5 {
+		t.Errorf("Size = %d, want 5", entry.Size)
+	}
+
+	dst := filepath.Join(t.TempDir(), "dst")
+	if err := c.Restore(key, dst); err != nil {
+		t.Fatalf("Restore() unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dst, "sub", "file.txt"))
+	if err != nil || string(data) != "hello" {
+		t.Errorf("Restored content = %q (%v), want hello", string(data), err)
+	}
+
+	// Editing the restored copy leaves the cached content intact
+	if err := os.WriteFile(filepath.Join(dst, "sub", "file.txt"), []byte("edited"), 0644); err != nil {
+		t.Fatalf("Failed to edit restored file: %v", err)
+	}
+	results, err := c.Verify()
+	if err != nil || len(results) != 1 || results[0].Err != nil {
+		t.Errorf("Verify() = %+v, %v, want the entry unchanged", results, err)
+	}
+
+	entries, err := c.List()
+	if err != nil {
+		t.Fatalf("List() unexpected error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].URL != url {
+		t.Errorf("List() = %+v, want single entry for %s", entries, url)
+	}
+}
+
+func TestVerifyAndPrune(t *testing.T) {
+	c := New(t.TempDir())
+
+	src := filepath.Join(t.TempDir(), "file.txt")
+	if err := os.WriteFile(src, []byte("hello"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	key, url, _ := Key("http::https://example.com/file.txt", "")
+	if err := c.Store(Entry{Key: key, URL: url}, src); err != nil {
+		t.Fatalf("Store() unexpected error: %v", err)
+	}
+
+	results, err := c.Verify()
+	if err != nil || len(results) != 1 || results[0].Err != nil {
+		t.Fatalf("Verify() = %+v, %v, want one valid entry", results, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(c.Dir(), key, dataDir), []byte("tampered"), 0644); err != nil {
+		t.Fatalf("Failed to tamper with cache entry: %v", err)
+	}
+	results, err = c.Verify()
+	if err != nil || len(results) != 1 || results[0].Err == nil {
+		t.Errorf("Verify() = %+v, %v, want mismatch error", results, err)
+	}
+
+	removed, err := c.Prune(time.Hour)
+	if err != nil || len(removed) != 0 {
+		t.Errorf("Prune(1h) removed %d entries (%v), want 0", len(removed), err)
+	}
+
+	removed, err = c.Prune(0)
+	if err != nil || len(removed) != 1 {
+		t.Errorf("Prune(0) removed %d entries (%v), want 1", len(removed), err)
+	}
+	if _, ok := c.Lookup(key); ok {
+		t.Error("Lookup() found pruned entry")
+	}
+}
+
+func TestListSkipsInvalidEntries(t *testing.T) {
+	c := New(t.TempDir())
+
+	src := filepath.Join(t.TempDir(), "file.txt")
+	if err := os.WriteFile(src, []byte("hello"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	key, url, _ := Key("http::https://example.com/file.txt", "")
+	if err := c.Store(Entry{Key: key, URL: url}, src); err != nil {
+		t.Fatalf("Store() unexpected error: %v", err)
+	}
+
+	for name, meta := range map[string]string{
+		"short":    `{"key": "short"}`,
+		"empty":    `{"key": ""}`,
+		"mismatch": `{"key": "` + key + `"}`,
+		"broken":   `{`,
+	} {
+		if err := os.MkdirAll(filepath.Join(c.Dir(), name), 0755); err != nil {
+			t.Fatalf("Failed to create cache entry: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(c.Dir(), name, metaFile), []byte(meta), 0644); err != nil {
+			t.Fatalf("Failed to create cache entry: %v", err)
+		}
+	}
+
+	entries, err := c.List()
+	if err != nil || len(entries) != 1 || entries[0].Key != key {
+		t.Fatalf("List() = %+v, %v, want only the valid entry", entries, err)
+	}
+
+	if _, err := c.Prune(0); err != nil {
+		t.Fatalf("Prune() unexpected error: %v", err)
+	}
+	if _, err := os.Stat(c.Dir()); err != nil {
+		t.Errorf("Prune() removed the cache directory: %v", err)
+	}
+}
@@ -0,0 +1,274 @@
+package checksum
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Supported checksum algorithms
+const (
+	MD5    = "md5"
+	SHA256 = "sha256"
+	SHA512 = "sha512"
+)
+
+// vcsDirs are directories excluded from directory digests
+var vcsDirs = map[string]bool{".git": true, ".hg": true}
+
+// Checksum represents an expected digest for a downloaded artifact.
+// Either Value is set, or URL points to a checksum file that has to be
+// downloaded and searched for the artifact before verification.
+type Checksum struct {
+	Algorithm string
+	Value     string
+	URL       string
+}
+
+// MismatchError is returned when the digest of an artifact does not match
+// the expected checksum
+type MismatchError struct {
+	Path     string
+	Expected string
+	Actual   string
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("checksum mismatch for %s: expected %s, got %s", e.Path, e.Expected, e.Actual)
+}
+
+// Parse parses a checksum specification. Supported forms are
+// "<algo>:<hex>" (md5, sha256, sha512), "file:<url>" and a plain
+// http(s) URL pointing to a checksum file.
+func Parse(spec string) (*Checksum, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("checksum is empty")
+	}
+
+	if strings.HasPrefix(spec, "http://") || strings.HasPrefix(spec, "https://") {
+		return &Checksum{URL: spec}, nil
+	}
+
+	algo, value, ok := strings.Cut(spec, ":")
+	if !ok {
+		return nil, fmt.Errorf("checksum %q must be in the form <algo>:<hex> or a checksum file URL", spec)
+	}
+
+	algo = strings.ToLower(algo)
+	if algo == "file" {
+		if value == "" {
+			return nil, fmt.Errorf("checksum file URL is empty")
+		}
+		return &Checksum{URL: value}, nil
+	}
+
+	return newChecksum(algo, value)
+}
+
+// newChecksum validates a digest against the given algorithm
+func newChecksum(algo, value string) (*Checksum, error) {
+	value = strings.ToLower(value)
+	if _, err := hex.DecodeString(value); err != nil {
+		return nil, fmt.Errorf("invalid %s checksum %q: not a hex string", algo, value)
+	}
+
+	h, err := newHash(algo)
+	if err != nil {
+		return nil, err
+	}
+	if len(value) != h.Size()*2 {
+		return nil, fmt.Errorf("invalid %s checksum %q: expected %d hex characters", algo, value, h.Size()*2)
+	}
+
+	return &Checksum{Algorithm: algo, Value: value}, nil
+}
+
+// String returns the checksum in its textual form
+func (c *Checksum) String() string {
+	if c.URL != "" {
+		return "file:" + c.URL
+	}
+	return c.Algorithm + ":" + c.Value
+}
+
+// Verify computes the digest of path and compares it with the expected value
+func (c *Checksum) Verify(path string) error {
+	if c.URL != "" {
+		return fmt.Errorf("checksum file %s has not been resolved", c.URL)
+	}
+
+	actual, err := Sum(c.Algorithm, path)
+	if err != nil {
+		return err
+	}
+
+	if actual != c.Value {
+		return &MismatchError{
+			Path:     path,
+			Expected: c.Algorithm + ":" + c.Value,
+			Actual:   c.Algorithm + ":" + actual,
+		}
+	}
+
+	return nil
+}
+
+// FromFile searches the content of a checksum file for the entry that
+// belongs to filename. Both GNU ("<hex>  file") and BSD
+// ("SHA256 (file) = <hex>") styles are supported. A file with a single
+// entry is accepted regardless of the file name it refers to.
+func FromFile(r io.Reader, filename string) (*Checksum, error) {
+	var entries [][2]string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.Fields(line)
+		switch {
+		case len(parts) == 1:
+			entries = append(entries, [2]string{parts[0], ""})
+		case len(parts) == 2:
+			name := strings.TrimLeft(parts[1], "*?")
+			entries = append(entries, [2]string{parts[0], name})
+		case len(parts) == 4 && parts[2] == "=" && strings.HasPrefix(parts[1], "(") && strings.HasSuffix(parts[1], ")"):
+			name := parts[1][1 : len(parts[1])-1]
+			entries = append(entries, [2]string{strings.ToLower(parts[0]) + ":" + parts[3], name})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read checksum file: %w", err)
+	}
+
+	for _, entry := range entries {
+		name := strings.TrimPrefix(entry[1], "./")
+		if len(entries) == 1 || name == filename || filepath.Base(name) == filename {
+			return fromDigest(entry[0])
+		}
+	}
+
+	return nil, fmt.Errorf("no checksum found for %s", filename)
+}
+
+// fromDigest parses "<algo>:<hex>" or a bare hex digest, guessing the
+// algorithm from its length
+func fromDigest(digest string) (*Checksum, error) {
+	if algo, value, ok := strings.Cut(digest, ":"); ok {
+		return newChecksum(strings.ToLower(algo), value)
+	}
+
+	switch len(digest) {
+	case md5.Size * 2:
+		return newChecksum(MD5, digest)
+	case sha256.Size * 2:
+		return newChecksum(SHA256, digest)
+	case sha512.Size * 2:
+		return newChecksum(SHA512, digest)
+	}
+
+	return nil, fmt.Errorf("cannot determine checksum algorithm for %q", digest)
+}
+
+// Sum returns the hex digest of path. Regular files are hashed by content;
+// directories are hashed over the sorted list of relative paths and the
+// digests of the files they contain, so equal trees produce equal sums.
+// VCS metadata directories are skipped.
+func Sum(algo, path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+
+	if !info.IsDir() {
+		return sumFile(algo, path)
+	}
+
+	var files []string
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && p != path && IsVCSDir(d.Name()) {
+			return filepath.SkipDir
+		}
+		if d.Type().IsRegular() {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(files)
+
+	h, err := newHash(algo)
+	if err != nil {
+		return "", err
+	}
+	for _, file := range files {
+		rel, err := filepath.Rel(path, file)
+		if err != nil {
+			return "", err
+		}
+		sum, err := sumFile(algo, file)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s\x00%s\n", filepath.ToSlash(rel), sum)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// sumFile returns the hex digest of a single file
+func sumFile(algo, path string) (string, error) {
+	h, err := newHash(algo)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// IsVCSDir reports whether a directory name holds VCS metadata that is
+// excluded from directory digests
+func IsVCSDir(name string) bool {
+	return vcsDirs[name]
+}
+
+// newHash returns a hash implementation for the algorithm
+func newHash(algo string) (hash.Hash, error) {
+	switch algo {
+	case MD5:
+		return md5.New(), nil
+	case SHA256:
+		return sha256.New(), nil
+	case SHA512:
+		return sha512.New(), nil
+	}
+	return nil, fmt.Errorf("unsupported checksum algorithm %q", algo)
+}
@@ -0,0 +1,160 @@
+package checksum
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	helloSHA256 = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	helloMD5    = "5d41402abc4b2a76b9719d911017c592"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		spec      string
+		wantAlgo  string
+		wantURL   string
+		wantError bool
+	}{
+		{name: "sha256", spec: "sha256:" + helloSHA256, wantAlgo: SHA256},
+		{name: "upper case algo", spec: "SHA256:" + strings.ToUpper(helloSHA256), wantAlgo: SHA256},
+		{name: "md5", spec: "md5:" + helloMD5, wantAlgo: MD5},
+		{name: "file url", spec: "file:https://example.com/SHA256SUMS", wantURL: "https://example.com/SHA256SUMS"},
+		{name: "plain url", spec: "https://example.com/SHA256SUMS", wantURL: "https://example.com/SHA256SUMS"},
+		{name: "empty", spec: "", wantError: true},
+		{name: "missing algo", spec: helloSHA256, wantError: true},
+		{name: "unsupported algo", spec: "crc32:abcd", wantError: true},
+		{name: "wrong length", spec: "sha256:" + helloMD5, wantError: true},
+		{name: "not hex", spec: "md5:zz41402abc4b2a76b9719d911017c592", wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Parse(tt.spec)
+			if tt.wantError {
+				if err == nil {
+					t.Errorf("Parse(%q) expected error, got nil", tt.spec)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q) unexpected error: %v", tt.spec, err)
+			}
+			if c.Algorithm != tt.wantAlgo {
+				t.Errorf("Algorithm = %s, want %s", c.Algorithm, tt.wantAlgo)
+			}
+			if c.URL != tt.wantURL {
+				t.Errorf("URL = %s, want %s", c.URL, tt.wantURL)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "hello.txt")
+	if err := os.WriteFile(file, []byte("hello"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	c, err := Parse("sha256:" + helloSHA256)
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	if err := c.Verify(file); err != nil {
+		t.Errorf("Verify() unexpected error: %v", err)
+	}
+
+	c, err = Parse("md5:00000000000000000000000000000000")
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	err = c.Verify(file)
+	var mismatch *MismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("Verify() error = %v, want MismatchError", err)
+	}
+	if mismatch.Actual != "md5:"+helloMD5 {
+		t.Errorf("Actual = %s, want md5:%s", mismatch.Actual, helloMD5)
+	}
+}
+
+func TestFromFile(t *testing.T) {
+	content := `# release checksums
+` + helloSHA256 + `  hello.txt
+` + strings.Repeat("a", 64) + ` *other.txt
+SHA256 (bsd.txt) = ` + strings.Repeat("b", 64) + `
+`
+
+	tests := []struct {
+		name      string
+		filename  string
+		want      string
+		wantError bool
+	}{
+		{name: "gnu style", filename: "hello.txt", want: helloSHA256},
+		{name: "binary marker", filename: "other.txt", want: strings.Repeat("a", 64)},
+		{name: "bsd style", filename: "bsd.txt", want: strings.Repeat("b", 64)},
+		{name: "missing entry", filename: "missing.txt", wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := FromFile(strings.NewReader(content), tt.filename)
+			if tt.wantError {
+				if err == nil {
+					t.Error("FromFile() expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FromFile() unexpected error: %v", err)
+			}
+			if c.Value != tt.want {
+				t.Errorf("Value = %s, want %s", c.Value, tt.want)
+			}
+		})
+	}
+}
+
+func TestSumDirectory(t *testing.T) {
+	dirA := t.TempDir()
+	dirB := t.TempDir()
+
+	for _, dir := range []string{dirA, dirB} {
+		if err := os.MkdirAll(filepath.Join(dir, "sub"), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "sub", "a.txt"), []byte("a"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	sumA, err := Sum(SHA256, dirA)
+	if err != nil {
+		t.Fatalf("Sum() unexpected error: %v", err)
+	}
+	sumB, err := Sum(SHA256, dirB)
+	if err != nil {
+		t.Fatalf("Sum() unexpected error: %v", err)
+	}
+	if sumA != sumB {
+		t.Errorf("Sum() of equal trees differ: %s != %s", sumA, sumB)
+	}
+
+	if err := os.WriteFile(filepath.Join(dirB, "extra.txt"), []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	sumB, err = Sum(SHA256, dirB)
+	if err != nil {
+		t.Fatalf("Sum() unexpected error: %v", err)
+	}
+	if sumA == sumB {
+		t.Error("Sum() of different trees should differ")
+	}
+}
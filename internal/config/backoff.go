@@ -0,0 +1,95 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Default backoff policy between retries
+const (
+	DefaultBackoffInitial    = time.Second
+	DefaultBackoffMax        = 30 * time.Second
+	DefaultBackoffMultiplier = 2.0
+	DefaultBackoffJitter     = 0.2
+)
+
+// Backoff configures the exponential wait between retries. The n-th retry
+// waits initial * multiplier^(n-1), capped at max and randomized by up to
+// jitter times the wait in either direction. Fields are pointers so that an
+// explicit zero, e.g. jitter: 0, is told apart from an unset field.
+type Backoff struct {
+	Initial    *time.Duration `yaml:"initial,omitempty"`
+	Max        *time.Duration `yaml:"max,omitempty"`
+	Multiplier *float64       `yaml:"multiplier,omitempty"`
+	Jitter     *float64       `yaml:"jitter,omitempty"`
+}
+
+// Merge returns the backoff with the fields set in o taking precedence
+func (b Backoff) Merge(o *Backoff) Backoff {
+	if o == nil {
+		return b
+	}
+	if o.Initial != nil {
+		b.Initial = o.Initial
+	}
+	if o.Max != nil {
+		b.Max = o.Max
+	}
+	if o.Multiplier != nil {
+		b.Multiplier = o.Multiplier
+	}
+	if o.Jitter != nil {
+		b.Jitter = o.Jitter
+	}
+	return b
+}
+
+// setDefaults fills in the default policy for unset fields
+func (b *Backoff) setDefaults() {
+	initial, maxWait := DefaultBackoffInitial, DefaultBackoffMax
+	multiplier, jitter := DefaultBackoffMultiplier, DefaultBackoffJitter
+	*b = Backoff{
+		Initial:    &initial,
+		Max:        &maxWait,
+		Multiplier: &multiplier,
+		Jitter:     &jitter,
+	}.Merge(b)
+}
+
+// Values returns the fields of the backoff, zero for unset ones
+func (b Backoff) Values() (initial, maxWait time.Duration, multiplier, jitter float64) {
+	if b.Initial != nil {
+		initial = *b.Initial
+	}
+	if b.Max != nil {
+		maxWait = *b.Max
+	}
+	if b.Multiplier != nil {
+		multiplier = *b.Multiplier
+	}
+	if b.Jitter != nil {
+		jitter = *b.Jitter
+	}
+	return initial, maxWait, multiplier, jitter
+}
+
+// validate checks the fields that are set
+func (b *Backoff) validate() error {
+	initial, maxWait, multiplier, jitter := b.Values()
+	if initial < 0 {
+		return fmt.Errorf("backoff initial must not be negative")
+	}
+	if maxWait < 0 {
+		return fmt.Errorf("backoff max must not be negative")
+	}
+	if initial > 0 && maxWait > 0 && maxWait < initial {
+		return fmt.Errorf("backoff max must not be less than initial")
+	}
+	if b.Multiplier != nil && multiplier < 1 {
+		return fmt.Errorf("backoff multiplier must be at least 1")
+	}
+	if jitter < 0 || jitter > 1 {
+		return fmt.Errorf("backoff jitter must be between 0 and 1")
+	}
+	return nil
+}
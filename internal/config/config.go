@@ -1,35 +1,122 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
-	"gopkg.in/yaml.v3"
+	"github.com/universal-development/go-getter-file/internal/checksum"
 )
 
+// CacheDirEnv is the environment variable that enables the download cache
+// when cache-dir is not set in the configuration file
+const CacheDirEnv = "GO_GETTER_FILE_CACHE_DIR"
+
 // Config represents the global configuration for all sources
 type Config struct {
 	Parallelism  int           `yaml:"parallelism,omitempty"`
 	Retries      int           `yaml:"retries,omitempty"`
 	Timeout      time.Duration `yaml:"timeout,omitempty"`
 	GoGetterPath string        `yaml:"go-getter-path,omitempty"`
+	CacheDir     string        `yaml:"cache-dir,omitempty"`
+	BaseDir      string        `yaml:"base-dir,omitempty"`
+	Backoff      Backoff       `yaml:"backoff,omitempty"`
+	// OutputRoot confines all destinations to this directory. Downloaded
+	// content is checked for symlinks and special files escaping it.
+	OutputRoot string `yaml:"output-root,omitempty"`
+	// Getters lists the go-getter getters sources may use, e.g. http or
+	// git. All getters are allowed when it is empty.
+	Getters []string `yaml:"getters,omitempty"`
 }
 
-// Source represents a single source to fetch
+// Source modes mapping to the go-getter request modes
+const (
+	ModeAny  = "any"
+	ModeFile = "file"
+	ModeDir  = "dir"
+)
+
+// Source represents a single source to fetch. Optional fields override the
+// global configuration for this source only.
 type Source struct {
-	URL       string        `yaml:"url"`
-	Dest      string        `yaml:"dest"`
-	Timeout   time.Duration `yaml:"timeout,omitempty"`
-	Recursive bool          `yaml:"recursive,omitempty"`
+	// Name identifies the source in version 2 files, which key sources by name
+	Name         string        `yaml:"-"`
+	URL          string        `yaml:"url"`
+	Dest         string        `yaml:"dest"`
+	Timeout      time.Duration `yaml:"timeout,omitempty"`
+	Retries      *int          `yaml:"retries,omitempty"`
+	Backoff      *Backoff      `yaml:"backoff,omitempty"`
+	GoGetterPath *string       `yaml:"go-getter-path,omitempty"`
+	Mode         string        `yaml:"mode,omitempty"`
+	Weight       int           `yaml:"weight,omitempty"`
+	Recursive    *bool         `yaml:"recursive,omitempty"`
+	Checksum     string        `yaml:"checksum,omitempty"`
+	// Hooks are only set in version 2 files
+	Hooks CommandHooks `yaml:"-"`
+
+	origin origin
+}
+
+// label names the source in messages: by name when it has one, otherwise by
+// its index i
+func (s Source) label(i int) string {
+	if s.Name != "" {
+		return fmt.Sprintf("source %q", s.Name)
+	}
+	return fmt.Sprintf("source %d", i)
 }
 
 // FileConfig represents the complete configuration file structure
 type FileConfig struct {
-	Version int      `yaml:"version"`
-	Name    string   `yaml:"name"`
-	Config  Config   `yaml:"config"`
-	Sources []Source `yaml:"sources"`
+	Version int    `yaml:"version"`
+	Name    string `yaml:"name"`
+	// Extends names a base configuration file whose vars, config, policy
+	// and sources this file inherits and overrides
+	Extends string `yaml:"extends,omitempty"`
+	// Include lists configuration files whose vars and sources are added
+	// to this file
+	Include []string          `yaml:"include,omitempty"`
+	Vars    map[string]string `yaml:"vars,omitempty"`
+	Config  Config            `yaml:"config"`
+	Policy  *Policy           `yaml:"policy,omitempty"`
+	Sources []Source          `yaml:"sources"`
+
+	origin origin
+}
+
+// Overrides holds values, typically from command line flags, that take
+// precedence over the global configuration of every configuration file.
+// Nil fields are left untouched.
+type Overrides struct {
+	Parallelism *int
+	Retries     *int
+	Timeout     *time.Duration
+	CacheDir    *string
+	OutputRoot  *string
+}
+
+// Apply applies the overrides to the given configuration
+func (o Overrides) Apply(c *Config) {
+	if o.Parallelism != nil {
+		c.Parallelism = *o.Parallelism
+	}
+	if o.Retries != nil {
+		c.Retries = *o.Retries
+	}
+	if o.Timeout != nil {
+		c.Timeout = *o.Timeout
+	}
+	if o.CacheDir != nil {
+		c.CacheDir = *o.CacheDir
+	}
+	if o.OutputRoot != nil {
+		c.OutputRoot = *o.OutputRoot
+	}
 }
 
 // SetDefaults sets default values for the configuration
@@ -43,49 +130,224 @@ func (c *FileConfig) SetDefaults() {
 	if c.Config.Timeout == 0 {
 		c.Config.Timeout = 30 * time.Second
 	}
+	if c.Config.CacheDir == "" {
+		c.Config.CacheDir = os.Getenv(CacheDirEnv)
+	}
+	c.Config.Backoff.setDefaults()
+}
+
+// LoadError is returned when a configuration cannot be parsed or is invalid
+type LoadError struct {
+	// Path is the configuration file, empty for configurations from memory
+	Path string
+	// Invalid is set when the file was parsed but the configuration is invalid
+	Invalid bool
+	Err     error
 }
 
-// LoadConfig loads a configuration file from the given path
-func LoadConfig(path string) (*FileConfig, error) {
+func (e *LoadError) Error() string {
+	what := "config"
+	if e.Path != "" {
+		what = "config file " + e.Path
+	}
+	msg := e.Err.Error()
+	if strings.Contains(msg, "\n") {
+		msg = "\n  " + strings.ReplaceAll(msg, "\n", "\n  ")
+	} else {
+		msg = " " + msg
+	}
+	if e.Invalid {
+		return fmt.Sprintf("invalid %s:%s", what, msg)
+	}
+	return fmt.Sprintf("failed to parse %s:%s", what, msg)
+}
+
+func (e *LoadError) Unwrap() error {
+	return e.Err
+}
+
+// LoadConfig loads a configuration file from the given path in the format
+// of its extension, resolves variable references and validates the result
+func LoadConfig(path string, opts ...LoadOption) (*FileConfig, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
 	}
 
+	config, err := parse(data, path, opts)
+	var loadErr *LoadError
+	if errors.As(err, &loadErr) {
+		loadErr.Path = path
+	}
+	return config, err
+}
+
+// Parse parses a configuration from YAML data, or data in the format set
+// with WithFormat, resolves variable references and validates the result.
+// Relative extends and include paths are resolved against the working
+// directory.
+func Parse(data []byte, opts ...LoadOption) (*FileConfig, error) {
+	return parse(data, "", opts)
+}
+
+// parse parses a configuration read from path, empty for configurations
+// from memory. Unknown fields and every validation problem are reported
+// with their positions in the file.
+func parse(data []byte, path string, opts []LoadOption) (*FileConfig, error) {
+	var o loadOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	format := FormatFor(path)
+	if o.format != "" {
+		var err error
+		if format, err = formatNamed(o.format); err != nil {
+			return nil, &LoadError{Err: err}
+		}
+	}
+
+	// Problems found while decoding are reported together with those of
+	// the validation, e.g. a misspelled dest key also leaves dest unset
+	var p problems
 	var config FileConfig
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	if err := decode(data, path, format, &config); err != nil {
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			return nil, &LoadError{Err: err}
+		}
+		p.errs = validationErr.Errors
+	}
+
+	var dir string
+	var stack []string
+	if path != "" {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, &LoadError{Err: err}
+		}
+		dir, stack = filepath.Dir(abs), []string{abs}
+	}
+
+	if err := config.resolveIncludes(dir, stack); err != nil {
+		return nil, &LoadError{Invalid: true, Err: errors.Join(p.err(), err)}
+	}
+
+	if err := config.interpolate(o.vars); err != nil {
+		return nil, &LoadError{Invalid: true, Err: errors.Join(p.err(), err)}
 	}
 
 	config.SetDefaults()
 
-	if err := config.Validate(); err != nil {
-		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	config.validate(&p)
+	config.checkPolicy(o.policy, &p)
+	p.sort()
+	if err := p.err(); err != nil {
+		return nil, &LoadError{Invalid: true, Err: err}
 	}
 
 	return &config, nil
 }
 
-// Validate validates the configuration
+// ResolveDests makes relative source destinations relative to base-dir.
+// A relative base-dir, or the lack of one, is resolved against root, which
+// is normally the directory of the configuration file. With an empty root
+// and no base-dir, destinations are left relative to the working directory.
+// A relative output-root is resolved against root as well.
+func (c *FileConfig) ResolveDests(root string) {
+	if c.Config.OutputRoot != "" && !filepath.IsAbs(c.Config.OutputRoot) && root != "" {
+		c.Config.OutputRoot = filepath.Join(root, c.Config.OutputRoot)
+	}
+
+	base := c.Config.BaseDir
+	if base == "" {
+		base = root
+	} else if !filepath.IsAbs(base) && root != "" {
+		base = filepath.Join(root, base)
+	}
+
+	if base == "" {
+		return
+	}
+
+	for i := range c.Sources {
+		if !filepath.IsAbs(c.Sources[i].Dest) {
+			c.Sources[i].Dest = filepath.Join(base, c.Sources[i].Dest)
+		}
+	}
+}
+
+// Validate validates the configuration and returns a *ValidationError
+// listing every problem found
 func (c *FileConfig) Validate() error {
+	var p problems
+	c.validate(&p)
+	return p.err()
+}
+
+// validate adds the problems of the configuration to p
+func (c *FileConfig) validate(p *problems) {
 	if c.Version == 0 {
-		return fmt.Errorf("version is required")
+		p.add(c.origin, fmt.Errorf("version is required"), "version")
+	} else if _, ok := decoders[c.Version]; !ok {
+		p.add(c.origin, fmt.Errorf("unsupported version %d, expected %s", c.Version, supportedVersions()), "version")
 	}
 	if c.Name == "" {
-		return fmt.Errorf("name is required")
+		p.add(c.origin, fmt.Errorf("name is required"), "name")
 	}
 	if len(c.Sources) == 0 {
-		return fmt.Errorf("at least one source is required")
+		p.add(c.origin, fmt.Errorf("at least one source is required"), "sources")
+	}
+	if err := c.Config.Backoff.validate(); err != nil {
+		p.add(c.origin, fmt.Errorf("config: %w", err), "config", "backoff")
+	}
+	seen := make(map[string]bool)
+	for i, name := range c.Config.Getters {
+		if name == "" {
+			p.add(c.origin, fmt.Errorf("config: getter names must not be empty"), "config", "getters", strconv.Itoa(i))
+		} else if seen[name] {
+			p.add(c.origin, fmt.Errorf("config: duplicate getter %q", name), "config", "getters", strconv.Itoa(i))
+		}
+		seen[name] = true
+	}
+	if err := c.Policy.validate(); err != nil {
+		p.add(c.origin, fmt.Errorf("policy: %w", err), "policy")
+	} else {
+		c.checkPolicy(c.Policy, p)
 	}
 
 	for i, source := range c.Sources {
 		if source.URL == "" {
-			return fmt.Errorf("source %d: url is required", i)
+			p.add(source.origin, fmt.Errorf("%s: url is required", source.label(i)), "url")
 		}
 		if source.Dest == "" {
-			return fmt.Errorf("source %d: dest is required", i)
+			p.add(source.origin, fmt.Errorf("%s: dest is required", source.label(i)), "dest")
+		}
+		if source.Checksum != "" {
+			if _, err := checksum.Parse(source.Checksum); err != nil {
+				p.add(source.origin, fmt.Errorf("%s: %w", source.label(i), err), "checksum")
+			}
+		}
+		if source.Backoff != nil {
+			if err := source.Backoff.validate(); err != nil {
+				p.add(source.origin, fmt.Errorf("%s: %w", source.label(i), err), "backoff")
+			}
+		}
+		if source.Retries != nil && *source.Retries < 0 {
+			p.add(source.origin, fmt.Errorf("%s: retries must not be negative", source.label(i)), "retries")
+		}
+		switch source.Mode {
+		case "", ModeAny, ModeFile, ModeDir:
+		default:
+			p.add(source.origin, fmt.Errorf("%s: invalid mode %q, expected %s, %s or %s", source.label(i), source.Mode, ModeAny, ModeFile, ModeDir), "mode")
+		}
+		if source.Weight < 0 {
+			p.add(source.origin, fmt.Errorf("%s: weight must not be negative", source.label(i)), "weight")
+		}
+		if slices.ContainsFunc(slices.Concat(source.Hooks.Before, source.Hooks.After), func(command string) bool {
+			return strings.TrimSpace(command) == ""
+		}) {
+			p.add(source.origin, fmt.Errorf("%s: hook commands must not be empty", source.label(i)), "hooks")
 		}
 	}
-
-	return nil
 }
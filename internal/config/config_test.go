@@ -1,8 +1,12 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -169,6 +173,79 @@ func TestValidate(t *testing.T) {
 			wantError: true,
 			errorMsg:  "source 1: dest is required",
 		},
+		{
+			name: "source with invalid checksum",
+			config: FileConfig{
+				Version: 1,
+				Name:    "test-project",
+				Sources: []Source{
+					{URL: "https://example.com/file.txt", Dest: "local.txt", Checksum: "crc32:abcd"},
+				},
+			},
+			wantError: true,
+			errorMsg:  `source 0: unsupported checksum algorithm "crc32"`,
+		},
+		{
+			name: "invalid global backoff",
+			config: FileConfig{
+				Version: 1,
+				Name:    "test-project",
+				Config:  Config{Backoff: Backoff{Initial: ptr(10 * time.Second), Max: ptr(time.Second)}},
+				Sources: []Source{
+					{URL: "https://example.com/file.txt", Dest: "local.txt"},
+				},
+			},
+			wantError: true,
+			errorMsg:  "config: backoff max must not be less than initial",
+		},
+		{
+			name: "invalid source backoff",
+			config: FileConfig{
+				Version: 1,
+				Name:    "test-project",
+				Sources: []Source{
+					{URL: "https://example.com/file.txt", Dest: "local.txt", Backoff: &Backoff{Jitter: ptr(2.0)}},
+				},
+			},
+			wantError: true,
+			errorMsg:  "source 0: backoff jitter must be between 0 and 1",
+		},
+		{
+			name: "source with invalid mode",
+			config: FileConfig{
+				Version: 1,
+				Name:    "test-project",
+				Sources: []Source{
+					{URL: "https://example.com/file.txt", Dest: "local.txt", Mode: "tree"},
+				},
+			},
+			wantError: true,
+			errorMsg:  `source 0: invalid mode "tree", expected any, file or dir`,
+		},
+		{
+			name: "source with zero retries",
+			config: FileConfig{
+				Version: 1,
+				Name:    "test-project",
+				Sources: []Source{
+					{URL: "https://example.com/file.txt", Dest: "local.txt", Retries: new(int)},
+				},
+			},
+			wantError: false,
+		},
+		{
+			name: "duplicate getter",
+			config: FileConfig{
+				Version: 1,
+				Name:    "test-project",
+				Config:  Config{Getters: []string{"http", "git", "http"}},
+				Sources: []Source{
+					{URL: "https://example.com/file.txt", Dest: "local.txt"},
+				},
+			},
+			wantError: true,
+			errorMsg:  `config: duplicate getter "http"`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -322,3 +399,232 @@ func TestLoadConfigFileNotFound(t *testing.T) {
 		t.Error("LoadConfig() expected error for nonexistent file, got nil")
 	}
 }
+
+func TestLoadConfigVars(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("GO_GETTER_FILE_TEST_ROOT", "/opt/vendor")
+
+	tests := []struct {
+		name      string
+		content   string
+		vars      map[string]string
+		wantURL   string
+		wantDest  string
+		wantError string
+	}{
+		{
+			name: "vars section and env",
+			content: `version: 1
+name: "vars"
+vars:
+  version: "v1.0.0"
+  root: "${env:GO_GETTER_FILE_TEST_ROOT}/lib"
+sources:
+  - url: "https://example.com/${version}/file.txt"
+    dest: "${root}/${version}"
+`,
+			wantURL:  "https://example.com/v1.0.0/file.txt",
+			wantDest: "/opt/vendor/lib/v1.0.0",
+		},
+		{
+			name: "cli overrides vars section",
+			content: `version: 1
+name: "vars"
+vars:
+  version: "v1.0.0"
+sources:
+  - url: "https://example.com/${version}/file.txt"
+    dest: "out-$${literal}"
+`,
+			vars:     map[string]string{"version": "v2.0.0"},
+			wantURL:  "https://example.com/v2.0.0/file.txt",
+			wantDest: "out-${literal}",
+		},
+		{
+			name: "undefined variable",
+			content: `version: 1
+name: "vars"
+sources:
+  - url: "https://example.com/${version}/file.txt"
+    dest: "out"
+`,
+			wantError: `source 0: url: undefined variable "version"`,
+		},
+		{
+			name: "missing environment variable",
+			content: `version: 1
+name: "vars"
+sources:
+  - url: "https://example.com/file.txt"
+    dest: "${env:GO_GETTER_FILE_TEST_MISSING}"
+`,
+			wantError: `source 0: dest: environment variable "GO_GETTER_FILE_TEST_MISSING" is not set`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(tmpDir, tt.name+".yaml")
+			if err := os.WriteFile(filePath, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+
+			cfg, err := LoadConfig(filePath, WithVars(tt.vars))
+			if tt.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+					t.Errorf("LoadConfig() error = %v, want error containing %q", err, tt.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig() unexpected error: %v", err)
+			}
+			if cfg.Sources[0].URL != tt.wantURL {
+				t.Errorf("URL = %s, want %s", cfg.Sources[0].URL, tt.wantURL)
+			}
+			if cfg.Sources[0].Dest != tt.wantDest {
+				t.Errorf("Dest = %s, want %s", cfg.Sources[0].Dest, tt.wantDest)
+			}
+		})
+	}
+}
+
+func TestResolveDests(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseDir string
+		root    string
+		dest    string
+		want    string
+	}{
+		{name: "relative to root", root: "/configs", dest: "out", want: "/configs/out"},
+		{name: "absolute dest", root: "/configs", dest: "/abs/out", want: "/abs/out"},
+		{name: "relative base-dir", baseDir: "vendor", root: "/configs", dest: "out", want: "/configs/vendor/out"},
+		{name: "absolute base-dir", baseDir: "/vendor", root: "/configs", dest: "out", want: "/vendor/out"},
+		{name: "no root", dest: "out", want: "out"},
+		{name: "base-dir without root", baseDir: "vendor", dest: "out", want: "vendor/out"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &FileConfig{
+				Config:  Config{BaseDir: tt.baseDir},
+				Sources: []Source{{URL: "https://example.com/file", Dest: tt.dest}},
+			}
+			cfg.ResolveDests(tt.root)
+			if got := cfg.Sources[0].Dest; got != tt.want {
+				t.Errorf("Dest = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestBackoffMerge(t *testing.T) {
+	cfg := FileConfig{Config: Config{Backoff: Backoff{Max: ptr(time.Minute)}}}
+	cfg.SetDefaults()
+
+	want := Backoff{Initial: ptr(time.Second), Max: ptr(time.Minute), Multiplier: ptr(2.0), Jitter: ptr(0.2)}
+	if !reflect.DeepEqual(cfg.Config.Backoff, want) {
+		t.Errorf("Backoff = %v, want %v", fmt.Sprint(cfg.Config.Backoff.Values()), fmt.Sprint(want.Values()))
+	}
+
+	merged := cfg.Config.Backoff.Merge(&Backoff{Initial: ptr(5 * time.Second), Jitter: ptr(0.5)})
+	want = Backoff{Initial: ptr(5 * time.Second), Max: ptr(time.Minute), Multiplier: ptr(2.0), Jitter: ptr(0.5)}
+	if !reflect.DeepEqual(merged, want) {
+		t.Errorf("Merge() = %v, want %v", fmt.Sprint(merged.Values()), fmt.Sprint(want.Values()))
+	}
+}
+
+func TestBackoffExplicitZero(t *testing.T) {
+	cfg, err := Parse([]byte(`version: 1
+name: "project"
+config:
+  backoff:
+    initial: 0s
+    jitter: 0
+sources:
+  - url: "https://example.com/file.txt"
+    dest: "file.txt"
+`))
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+
+	initial, _, _, jitter := cfg.Config.Backoff.Values()
+	if cfg.Config.Backoff.Jitter == nil || jitter != 0 || cfg.Config.Backoff.Initial == nil || initial != 0 {
+		t.Errorf("Backoff = %v, want initial and jitter 0 kept over the defaults", fmt.Sprint(cfg.Config.Backoff.Values()))
+	}
+
+	merged := Backoff{Jitter: ptr(0.5)}.Merge(&Backoff{Jitter: ptr(0.0)})
+	if _, _, _, jitter := merged.Values(); jitter != 0 {
+		t.Errorf("Merge() jitter = %v, want 0 to take precedence", jitter)
+	}
+}
+
+func TestConfineDest(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(root, "link")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		root    string
+		dest    string
+		wantErr bool
+	}{
+		{name: "no output root", dest: "/etc/passwd"},
+		{name: "inside", root: root, dest: filepath.Join(root, "vendor", "file.txt")},
+		{name: "traversal", root: root, dest: filepath.Join(root, "..", "file.txt"), wantErr: true},
+		{name: "absolute path outside", root: root, dest: filepath.Join(outside, "file.txt"), wantErr: true},
+		{name: "root itself", root: root, dest: root, wantErr: true},
+		{name: "through a symlink", root: root, dest: filepath.Join(root, "link", "file.txt"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{OutputRoot: tt.root}
+			err := cfg.ConfineDest(tt.dest)
+			if tt.wantErr != errors.Is(err, ErrOutsideRoot) {
+				t.Errorf("ConfineDest(%q) error = %v, want outside root: %v", tt.dest, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckDest(t *testing.T) {
+	project := t.TempDir()
+	configPath := filepath.Join(project, "configs", "project.go.getter.yaml")
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		dest    string
+		wantErr bool
+	}{
+		{name: "download directory", dest: filepath.Join(project, "configs", "vendor")},
+		{name: "sibling of the config directory", dest: filepath.Join(project, "vendor")},
+		{name: "config directory", dest: filepath.Join(project, "configs"), wantErr: true},
+		{name: "parent of the config directory", dest: project, wantErr: true},
+		{name: "working directory", dest: wd, wantErr: true},
+		{name: "parent of the working directory", dest: filepath.Dir(wd), wantErr: true},
+		{name: "filesystem root", dest: "/", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckDest(tt.dest, configPath)
+			if tt.wantErr != errors.Is(err, ErrProtectedDest) {
+				t.Errorf("CheckDest(%q) error = %v, want protected: %v", tt.dest, err, tt.wantErr)
+			}
+		})
+	}
+}
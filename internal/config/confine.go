@@ -0,0 +1,102 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ErrOutsideRoot is returned for destinations outside the output root
+var ErrOutsideRoot = errors.New("outside the output root")
+
+// ConfineDest returns an error wrapping ErrOutsideRoot when dest, with
+// symlinks resolved, is not inside the output root. Every destination is
+// allowed when no output root is configured.
+func (c Config) ConfineDest(dest string) error {
+	if c.OutputRoot == "" {
+		return nil
+	}
+
+	root, err := resolvePath(c.OutputRoot)
+	if err != nil {
+		return fmt.Errorf("failed to resolve output root %s: %w", c.OutputRoot, err)
+	}
+	resolved, err := resolvePath(dest)
+	if err != nil {
+		return fmt.Errorf("failed to resolve dest %s: %w", dest, err)
+	}
+
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == "." || !filepath.IsLocal(rel) {
+		return fmt.Errorf("dest %s is %w %s", dest, ErrOutsideRoot, c.OutputRoot)
+	}
+	return nil
+}
+
+// resolvePath returns the absolute form of path with the symlinks of its
+// longest existing prefix resolved, so paths that do not exist yet are
+// resolved as they will be created
+func resolvePath(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	var missing []string
+	for {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			return filepath.Join(append([]string{resolved}, missing...)...), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+
+		parent := filepath.Dir(path)
+		if parent == path {
+			return filepath.Join(append([]string{path}, missing...)...), nil
+		}
+		missing = append([]string{filepath.Base(path)}, missing...)
+		path = parent
+	}
+}
+
+// ErrProtectedDest is returned for destinations that replacing or removing
+// would destroy more than the downloaded content
+var ErrProtectedDest = errors.New("protected path")
+
+// CheckDest returns an error wrapping ErrProtectedDest when dest, with
+// symlinks resolved, is or contains the directory of the configuration
+// file at configPath, the working directory, the home directory or the
+// filesystem root. configPath is empty for configurations without a file.
+func CheckDest(dest, configPath string) error {
+	resolved, err := resolvePath(dest)
+	if err != nil {
+		return fmt.Errorf("failed to resolve dest %s: %w", dest, err)
+	}
+
+	type place struct{ name, path string }
+	protected := []place{{"the filesystem root", filepath.VolumeName(resolved) + string(filepath.Separator)}}
+	if configPath != "" {
+		protected = append(protected, place{"the directory of " + configPath, filepath.Dir(configPath)})
+	}
+	if wd, err := os.Getwd(); err == nil {
+		protected = append(protected, place{"the working directory", wd})
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		protected = append(protected, place{"the home directory", home})
+	}
+
+	for _, p := range protected {
+		path, err := resolvePath(p.path)
+		if err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(resolved, path); err == nil && filepath.IsLocal(rel) {
+			return fmt.Errorf("dest %s is a %w: it is or contains %s", dest, ErrProtectedDest, p.name)
+		}
+	}
+	return nil
+}
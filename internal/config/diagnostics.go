@@ -0,0 +1,252 @@
+package config
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// typeErrorPattern matches yaml.v3 type error messages, e.g. "line 5:
+// cannot unmarshal !!str `two` into int"
+var typeErrorPattern = regexp.MustCompile(`^line (\d+): (.*)$`)
+
+// FieldError is a problem with a single value of a configuration file.
+// Line and Column are zero when the position is unknown, e.g. for
+// configurations built in memory.
+type FieldError struct {
+	File   string
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *FieldError) Error() string {
+	var pos string
+	switch {
+	case e.Line == 0:
+	case e.Column == 0:
+		pos = fmt.Sprintf("%d: ", e.Line)
+	default:
+		pos = fmt.Sprintf("%d:%d: ", e.Line, e.Column)
+	}
+	if e.File != "" && pos != "" {
+		pos = e.File + ":" + pos
+	}
+	return pos + e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError lists every problem found in a configuration, one per line
+type ValidationError struct {
+	Errors []*FieldError
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		lines[i] = err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+// origin is the node a configuration or source was decoded from
+type origin struct {
+	file string
+	node *yaml.Node
+	// options is the node of the options of a version 2 source, where keys
+	// missing from node are looked up
+	options *yaml.Node
+}
+
+// at returns the position of the value at keys below the origin node, or
+// of its closest ancestor when a key is missing. Numeric keys index
+// sequences.
+func (o origin) at(keys ...string) (line, column int) {
+	if o.node == nil {
+		return 0, 0
+	}
+	n := docRoot(o.node)
+	if o.options != nil && len(keys) > 0 && child(n, keys[0]) == nil && child(o.options, keys[0]) != nil {
+		n = o.options
+	}
+	for _, key := range keys {
+		next := child(n, key)
+		if next == nil {
+			break
+		}
+		n = next
+	}
+	return n.Line, n.Column
+}
+
+// child returns the value of key in a mapping node or the element at index
+// key in a sequence node
+func child(n *yaml.Node, key string) *yaml.Node {
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == key {
+				return n.Content[i+1]
+			}
+		}
+	case yaml.SequenceNode:
+		if i, err := strconv.Atoi(key); err == nil && i >= 0 && i < len(n.Content) {
+			return n.Content[i]
+		}
+	}
+	return nil
+}
+
+// problems collects the errors found while validating a configuration
+type problems struct {
+	errs []*FieldError
+}
+
+// add records err for the value at keys below o
+func (p *problems) add(o origin, err error, keys ...string) {
+	line, column := o.at(keys...)
+	p.errs = append(p.errs, &FieldError{File: o.file, Line: line, Column: column, Err: err})
+}
+
+// err returns a *ValidationError with the collected problems, or nil
+func (p *problems) err() error {
+	if len(p.errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: p.errs}
+}
+
+// sort orders the problems by their position in the file
+func (p *problems) sort() {
+	slices.SortStableFunc(p.errs, func(a, b *FieldError) int {
+		return cmp.Or(cmp.Compare(a.Line, b.Line), cmp.Compare(a.Column, b.Column))
+	})
+}
+
+// decodeStrict decodes the document doc into v, rejecting fields v does
+// not have. An empty document leaves v untouched. Unknown fields and type
+// errors are returned as a *ValidationError with their positions.
+func decodeStrict(doc *yaml.Node, file string, v any) error {
+	if doc.Kind == 0 || doc.Kind == yaml.DocumentNode && len(doc.Content) == 0 {
+		return nil
+	}
+
+	var p problems
+	checkFields(doc, reflect.TypeOf(v), file, &p)
+	err := doc.Decode(v)
+	var typeErr *yaml.TypeError
+	if err != nil && !errors.As(err, &typeErr) {
+		return err
+	}
+	if typeErr != nil {
+		for _, msg := range typeErr.Errors {
+			fieldErr := &FieldError{File: file}
+			if m := typeErrorPattern.FindStringSubmatch(msg); m != nil {
+				fieldErr.Line, _ = strconv.Atoi(m[1])
+				msg = m[2]
+			}
+			fieldErr.Err = errors.New(msg)
+			p.errs = append(p.errs, fieldErr)
+		}
+	}
+	p.sort()
+	return p.err()
+}
+
+// checkFields records the mapping keys below n that the type t they are
+// decoded into has no field for, the way yaml.v3 rejects them with known
+// fields but with their columns and for documents of every format
+func checkFields(n *yaml.Node, t reflect.Type, file string, p *problems) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	n = docRoot(n)
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			return
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			if key.ShortTag() == "!!merge" {
+				checkFields(value, t, file, p)
+				continue
+			}
+			field, ok := fields[key.Value]
+			if !ok {
+				p.errs = append(p.errs, &FieldError{
+					File:   file,
+					Line:   key.Line,
+					Column: key.Column,
+					Err:    fmt.Errorf("unknown field %q", key.Value),
+				})
+				continue
+			}
+			checkFields(value, field, file, p)
+		}
+	case reflect.Slice:
+		if n.Kind == yaml.SequenceNode {
+			for _, item := range n.Content {
+				checkFields(item, t.Elem(), file, p)
+			}
+		}
+	case reflect.Map:
+		if n.Kind == yaml.MappingNode {
+			for i := 1; i < len(n.Content); i += 2 {
+				checkFields(n.Content[i], t.Elem(), file, p)
+			}
+		}
+	}
+}
+
+// yamlFields maps the yaml names of the fields of struct type t to their
+// types
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+// docRoot returns the top-level node of a document
+func docRoot(doc *yaml.Node) *yaml.Node {
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		return doc.Content[0]
+	}
+	return doc
+}
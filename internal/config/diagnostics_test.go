@@ -0,0 +1,118 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfigDiagnostics(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{
+			name: "unknown fields",
+			content: `version: 1
+name: "typos"
+config:
+  retires: 2
+sources:
+  - url: "https://example.com/file.txt"
+    destination: "file.txt"
+`,
+			want: []string{
+				`:4:3: unknown field "retires"`,
+				`:6:5: source 0: dest is required`,
+				`:7:5: unknown field "destination"`,
+			},
+		},
+		{
+			name: "type and validation errors",
+			content: `version: 1
+name: "mixed"
+config:
+  parallelism: "two"
+sources:
+  - url: ""
+    dest: "a.txt"
+`,
+			want: []string{
+				":4: cannot unmarshal !!str `two` into int",
+				`:6:10: source 0: url is required`,
+			},
+		},
+		{
+			name: "all validation errors",
+			content: `version: 1
+name: "invalid"
+sources:
+  - url: "https://example.com/a.txt"
+    dest: "a.txt"
+    mode: "weird"
+  - dest: "b.txt"
+    weight: -1
+`,
+			want: []string{
+				`:6:11: source 0: invalid mode "weird", expected any, file or dir`,
+				`:7:5: source 1: url is required`,
+				`:8:13: source 1: weight must not be negative`,
+			},
+		},
+		{
+			name: "undefined variables",
+			content: `version: 1
+name: "vars"
+sources:
+  - url: "https://example.com/${a}"
+    dest: "${b}"
+`,
+			want: []string{
+				`:4:10: source 0: url: undefined variable "a"`,
+				`:5:11: source 0: dest: undefined variable "b"`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "test.go.getter.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+
+			_, err := LoadConfig(path)
+			var loadErr *LoadError
+			if !errors.As(err, &loadErr) || !loadErr.Invalid {
+				t.Fatalf("LoadConfig() error = %v, want an invalid config *LoadError", err)
+			}
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("LoadConfig() error = %v, want a *ValidationError", err)
+			}
+			if len(validationErr.Errors) != len(tt.want) {
+				t.Fatalf("got %d errors, want %d: %v", len(validationErr.Errors), len(tt.want), err)
+			}
+			for i, want := range tt.want {
+				want = path + want
+				if got := validationErr.Errors[i].Error(); got != want {
+					t.Errorf("Errors[%d] = %q, want %q", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadPolicyUnknownField(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(path, []byte("allowed-host: [\"github.com\"]\n"), 0644); err != nil {
+		t.Fatalf("Failed to create policy file: %v", err)
+	}
+
+	var validationErr *ValidationError
+	if _, err := LoadPolicy(path); !errors.As(err, &validationErr) {
+		t.Errorf("LoadPolicy() error = %v, want an unknown field error", err)
+	}
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format is a configuration file syntax. Every format is parsed into the
+// same YAML node tree, so all of them share the fields, versions,
+// validation and error positions of YAML files.
+type Format struct {
+	// Name identifies the format, e.g. "yaml"
+	Name  string
+	parse func(data []byte, file string) (*yaml.Node, error)
+}
+
+var (
+	yamlFormat = Format{Name: "yaml", parse: parseYAML}
+
+	// formats maps the extensions of configuration files to their format
+	formats = map[string]Format{
+		".yaml": yamlFormat,
+		".yml":  yamlFormat,
+		".json": {Name: "json", parse: parseJSON},
+		".toml": {Name: "toml", parse: parseTOML},
+		".hcl":  {Name: "hcl", parse: parseHCL},
+	}
+)
+
+// Extensions returns the extensions of the supported configuration file
+// formats, e.g. ".json", sorted
+func Extensions() []string {
+	return slices.Sorted(maps.Keys(formats))
+}
+
+// FormatFor returns the format of the file at path by its extension. Files
+// with other extensions are read as YAML.
+func FormatFor(path string) Format {
+	if format, ok := formats[strings.ToLower(filepath.Ext(path))]; ok {
+		return format
+	}
+	return yamlFormat
+}
+
+// formatNamed returns the format called name
+func formatNamed(name string) (Format, error) {
+	var names []string
+	for _, ext := range Extensions() {
+		if formats[ext].Name == name {
+			return formats[ext], nil
+		}
+		if !slices.Contains(names, formats[ext].Name) {
+			names = append(names, formats[ext].Name)
+		}
+	}
+	return Format{}, fmt.Errorf("unknown format %q, expected one of %s", name, strings.Join(names, ", "))
+}
+
+// WithFormat parses the configuration in the named format, e.g. "json",
+// instead of the format of the file extension, or YAML for Parse
+func WithFormat(name string) LoadOption {
+	return func(o *loadOptions) {
+		o.format = name
+	}
+}
+
+// parseYAML parses a YAML document
+func parseYAML(data []byte, _ string) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// parseJSON parses a JSON document. JSON is valid YAML, so once the syntax
+// is checked against the stricter JSON rules it is parsed as YAML to keep
+// the positions of its values.
+func parseJSON(data []byte, file string) (*yaml.Node, error) {
+	if err := json.Unmarshal(data, new(any)); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			line, column := lineColumn(data, int(syntaxErr.Offset)-1)
+			return nil, syntaxError("json", line, column, syntaxErr.Error())
+		}
+		return nil, fmt.Errorf("json: %w", err)
+	}
+	return parseYAML(data, file)
+}
+
+// syntaxError is an error at a position of a file in the named format
+func syntaxError(format string, line, column int, msg string) error {
+	return fmt.Errorf("%s: line %d, column %d: %s", format, line, column, msg)
+}
+
+// lineColumn returns the line and column of the byte at offset in data
+func lineColumn(data []byte, offset int) (line, column int) {
+	offset = max(0, min(offset, len(data)))
+	before := data[:offset]
+	line = bytes.Count(before, []byte("\n")) + 1
+	column = offset - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
+
+// scalarNode returns a scalar node with the given tag and value
+func scalarNode(tag, value string, line, column int) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value, Line: line, Column: column}
+}
+
+// mappingNode returns an empty mapping node
+func mappingNode(line, column int) *yaml.Node {
+	return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: line, Column: column}
+}
+
+// sequenceNode returns an empty sequence node
+func sequenceNode(line, column int) *yaml.Node {
+	return &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Line: line, Column: column}
+}
+
+// documentNode returns a document with root as its content
+func documentNode(root *yaml.Node) *yaml.Node {
+	return &yaml.Node{Kind: yaml.DocumentNode, Line: 1, Column: 1, Content: []*yaml.Node{root}}
+}
@@ -0,0 +1,337 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// formatFiles holds the same version 1 configuration in every format
+var formatFiles = map[string]string{
+	"project.go.getter.yaml": `version: 1
+name: "project"
+vars:
+  version: "v1.2.0"
+config:
+  parallelism: 2
+  backoff:
+    initial: 2s
+sources:
+  - url: "https://example.com/${version}/README.md"
+    dest: "docs/README.md"
+    timeout: 1m
+    retries: 5
+  - url: "https://example.com/archive.tar.gz"
+    dest: "vendor/archive"
+    mode: dir
+    recursive: true
+`,
+	"project.go.getter.json": `{
+	"version": 1,
+	"name": "project",
+	"vars": {"version": "v1.2.0"},
+	"config": {"parallelism": 2, "backoff": {"initial": "2s"}},
+	"sources": [
+		{
+			"url": "https://example.com/${version}/README.md",
+			"dest": "docs/README.md",
+			"timeout": "1m",
+			"retries": 5
+		},
+		{
+			"url": "https://example.com/archive.tar.gz",
+			"dest": "vendor/archive",
+			"mode": "dir",
+			"recursive": true
+		}
+	]
+}
+`,
+	"project.go.getter.toml": `version = 1
+name = "project"
+
+[vars]
+version = "v1.2.0"
+
+[config]
+parallelism = 2
+backoff = { initial = "2s" }
+
+[[sources]]
+url = "https://example.com/${version}/README.md"
+dest = "docs/README.md"
+timeout = "1m"
+retries = 5
+
+[[sources]]
+url = "https://example.com/archive.tar.gz"
+dest = "vendor/archive"
+mode = "dir"
+recursive = true
+`,
+	"project.go.getter.hcl": `version = 1
+name    = "project"
+
+vars = {
+  version = "v1.2.0"
+}
+
+config {
+  parallelism = 2
+  backoff {
+    initial = "2s"
+  }
+}
+
+source {
+  url     = "https://example.com/${version}/README.md"
+  dest    = "docs/README.md"
+  timeout = "1m"
+  retries = 5
+}
+
+source {
+  url       = "https://example.com/archive.tar.gz"
+  dest      = "vendor/archive"
+  mode      = "dir"
+  recursive = true
+}
+`,
+}
+
+func TestLoadConfigFormats(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, formatFiles)
+
+	for name := range formatFiles {
+		t.Run(filepath.Ext(name), func(t *testing.T) {
+			cfg, err := LoadConfig(filepath.Join(tmpDir, name))
+			if err != nil {
+				t.Fatalf("LoadConfig() unexpected error: %v", err)
+			}
+			if cfg.Name != "project" || cfg.Config.Parallelism != 2 || *cfg.Config.Backoff.Initial != 2*time.Second {
+				t.Errorf("config = %+v, want name, parallelism and backoff set", cfg)
+			}
+			if len(cfg.Sources) != 2 {
+				t.Fatalf("got %d sources, want 2", len(cfg.Sources))
+			}
+
+			readme, archive := cfg.Sources[0], cfg.Sources[1]
+			if readme.URL != "https://example.com/v1.2.0/README.md" || readme.Dest != "docs/README.md" {
+				t.Errorf("Sources[0] = %s -> %s, want the interpolated readme", readme.URL, readme.Dest)
+			}
+			if readme.Timeout != time.Minute || readme.Retries == nil || *readme.Retries != 5 {
+				t.Errorf("Sources[0] options = %+v, want timeout and retries", readme)
+			}
+			if archive.Mode != ModeDir || archive.Recursive == nil || !*archive.Recursive {
+				t.Errorf("Sources[1] options = %+v, want mode dir and recursive", archive)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFormatsV2(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{
+		"v2.go.getter.toml": `version = 2
+name = "v2"
+
+[hooks]
+after = ["echo done"]
+
+[sources.readme]
+url = "https://example.com/README.md"
+dest = "README.md"
+options = { timeout = "1m" }
+
+[sources.license]
+url = "https://example.com/LICENSE"
+dest = "LICENSE"
+`,
+		"v2.go.getter.hcl": `version = 2
+name    = "v2"
+
+hooks {
+  after = ["echo done"]
+}
+
+source "readme" {
+  url  = "https://example.com/README.md"
+  dest = "README.md"
+  options {
+    timeout = "1m"
+  }
+}
+
+source "license" {
+  url  = "https://example.com/LICENSE"
+  dest = "LICENSE"
+}
+`,
+	})
+
+	for _, name := range []string{"v2.go.getter.toml", "v2.go.getter.hcl"} {
+		t.Run(filepath.Ext(name), func(t *testing.T) {
+			cfg, err := LoadConfig(filepath.Join(tmpDir, name))
+			if err != nil {
+				t.Fatalf("LoadConfig() unexpected error: %v", err)
+			}
+			if cfg.Version != Version2 || len(cfg.Sources) != 2 {
+				t.Fatalf("LoadConfig() = version %d with %d sources, want version 2 with 2", cfg.Version, len(cfg.Sources))
+			}
+			readme, license := cfg.Sources[0], cfg.Sources[1]
+			if readme.Name != "readme" || license.Name != "license" {
+				t.Errorf("source names = %q, %q, want them in file order", readme.Name, license.Name)
+			}
+			if readme.Timeout != time.Minute || !slices.Equal(license.Hooks.After, []string{"echo done"}) {
+				t.Errorf("Sources = %+v, want options and hooks", cfg.Sources)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFormatErrors(t *testing.T) {
+	tests := []struct {
+		file      string
+		content   string
+		wantError string
+		invalid   bool
+	}{
+		{
+			file:      "typo.go.getter.json",
+			content:   "{\n  \"version\": 1,\n  \"name\": \"typo\",\n  \"sources\": [{\"url\": \"https://example.com/a\", \"destination\": \"a\"}]\n}\n",
+			wantError: `typo.go.getter.json:4:48: unknown field "destination"`,
+			invalid:   true,
+		},
+		{
+			file:      "syntax.go.getter.json",
+			content:   "{\n  \"version\": 1,\n  \"name\": \"syntax\",\n}\n",
+			wantError: "json: line 4, column 1: invalid character '}'",
+		},
+		{
+			file:      "typo.go.getter.toml",
+			content:   "version = 1\nname = \"typo\"\n\n[config]\nretires = 2\n",
+			wantError: `typo.go.getter.toml:5:1: unknown field "retires"`,
+			invalid:   true,
+		},
+		{
+			file:      "mode.go.getter.toml",
+			content:   "version = 1\nname = \"mode\"\n\n[[sources]]\nurl = \"https://example.com/a\"\ndest = \"a\"\nmode = \"weird\"\n",
+			wantError: `mode.go.getter.toml:7:8: source 0: invalid mode "weird"`,
+			invalid:   true,
+		},
+		{
+			file:      "duplicate.go.getter.toml",
+			content:   "version = 1\nname = \"duplicate\"\nname = \"again\"\n",
+			wantError: `toml: line 3, column 1: key "name" is already defined`,
+		},
+		{
+			file:      "typo.go.getter.hcl",
+			content:   "version = 1\nname    = \"typo\"\n\nsource {\n  url    = \"https://example.com/a\"\n  dest   = \"a\"\n  wieght = 2\n}\n",
+			wantError: `typo.go.getter.hcl:7:3: unknown field "wieght"`,
+			invalid:   true,
+		},
+		{
+			file:      "mode.go.getter.hcl",
+			content:   "version = 1\nname    = \"mode\"\n\nsource {\n  url  = \"https://example.com/a\"\n  dest = \"a\"\n  mode = \"weird\"\n}\n",
+			wantError: `mode.go.getter.hcl:7:10: source 0: invalid mode "weird", expected any, file or dir`,
+			invalid:   true,
+		},
+		{
+			file:      "labels.go.getter.hcl",
+			content:   "version = 2\nname = \"labels\"\n\nsource \"a\" {\n  url  = \"https://example.com/a\"\n  dest = \"a\"\n}\n\nsource {\n  url  = \"https://example.com/b\"\n  dest = \"b\"\n}\n",
+			wantError: "hcl: line 9, column 1: source blocks must either all have a label or none",
+		},
+		{
+			file:      "syntax.go.getter.hcl",
+			content:   "version = 1\nname = \"syntax\"\nsource {\n",
+			wantError: "hcl: line 3, column 8: Unclosed configuration block",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			writeFiles(t, tmpDir, map[string]string{tt.file: tt.content})
+
+			_, err := LoadConfig(filepath.Join(tmpDir, tt.file))
+			var loadErr *LoadError
+			if !errors.As(err, &loadErr) || loadErr.Invalid != tt.invalid {
+				t.Fatalf("LoadConfig() error = %v, want a *LoadError with Invalid %v", err, tt.invalid)
+			}
+			if !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("LoadConfig() error = %v, want it to contain %q", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestLoadConfigIncludeAcrossFormats(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{
+		"base.toml":  "[config]\nparallelism = 8\n",
+		"tools.json": `{"sources": [{"url": "https://example.com/tool", "dest": "tool"}]}`,
+		"main.go.getter.hcl": `version = 1
+name    = "main"
+extends = "base.toml"
+include = ["tools.json"]
+
+source {
+  url  = "https://example.com/README.md"
+  dest = "README.md"
+}
+`,
+	})
+
+	cfg, err := LoadConfig(filepath.Join(tmpDir, "main.go.getter.hcl"))
+	if err != nil {
+		t.Fatalf("LoadConfig() unexpected error: %v", err)
+	}
+	if cfg.Config.Parallelism != 8 {
+		t.Errorf("Parallelism = %d, want 8 from the TOML base", cfg.Config.Parallelism)
+	}
+	if len(cfg.Sources) != 2 || cfg.Sources[0].Dest != "tool" || cfg.Sources[1].Dest != "README.md" {
+		t.Errorf("Sources = %+v, want the JSON include followed by the HCL source", cfg.Sources)
+	}
+}
+
+func TestParseWithFormat(t *testing.T) {
+	data := []byte(`{"version": 1, "name": "json", "sources": [{"url": "https://example.com/a", "dest": "a"}]}`)
+	cfg, err := Parse(data, WithFormat("json"))
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	if cfg.Name != "json" || len(cfg.Sources) != 1 {
+		t.Errorf("Parse() = %+v, want the JSON configuration", cfg)
+	}
+
+	_, err = Parse(data, WithFormat("xml"))
+	if err == nil || !strings.Contains(err.Error(), `unknown format "xml", expected one of hcl, json, toml, yaml`) {
+		t.Errorf("Parse() error = %v, want an unknown format error", err)
+	}
+}
+
+func TestHCLVariables(t *testing.T) {
+	data := []byte(`version = 1
+name    = "vars"
+vars = {
+  host = "example.com"
+}
+
+source {
+  url  = "https://${host}/$${env:GO_GETTER_FILE_TEST_PATH}"
+  dest = "file.txt"
+}
+`)
+	t.Setenv("GO_GETTER_FILE_TEST_PATH", "file.txt")
+	cfg, err := Parse(data, WithFormat("hcl"))
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	if got := cfg.Sources[0].URL; got != "https://example.com/file.txt" {
+		t.Errorf("URL = %q, want variables resolved like in YAML", got)
+	}
+}
@@ -0,0 +1,260 @@
+package config
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"gopkg.in/yaml.v3"
+)
+
+// hclListBlocks maps the types of the blocks that may be repeated to the
+// key they are collected under. Without labels the blocks form a list, as
+// the sources of version 1; with a label they are keyed by it, as the
+// sources of version 2.
+var hclListBlocks = map[string]string{
+	"source": "sources",
+}
+
+// parseHCL parses an HCL document. Attributes become keys and blocks
+// become mappings under their type, e.g. config { parallelism = 2 }.
+// Variable references like ${version} are kept as they are written so
+// they are resolved like in the other formats.
+func parseHCL(data []byte, file string) (*yaml.Node, error) {
+	f, diags := hclsyntax.ParseConfig(data, file, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, hclError(diags)
+	}
+	root, err := hclBody(f.Body.(*hclsyntax.Body))
+	if err != nil {
+		return nil, err
+	}
+	return documentNode(root), nil
+}
+
+// hclBody converts the attributes and blocks of a body, in the order they
+// are written, to a mapping
+func hclBody(body *hclsyntax.Body) (*yaml.Node, error) {
+	start := body.SrcRange.Start
+	m := mappingNode(start.Line, start.Column)
+
+	type item struct {
+		pos   hcl.Pos
+		attr  *hclsyntax.Attribute
+		block *hclsyntax.Block
+	}
+	var items []item
+	for _, attr := range body.Attributes {
+		items = append(items, item{pos: attr.NameRange.Start, attr: attr})
+	}
+	for _, block := range body.Blocks {
+		items = append(items, item{pos: block.TypeRange.Start, block: block})
+	}
+	slices.SortFunc(items, func(a, b item) int {
+		return cmp.Compare(a.pos.Byte, b.pos.Byte)
+	})
+
+	groups := make(map[string]*yaml.Node)
+	for _, it := range items {
+		if it.attr != nil {
+			key := hclKey(it.attr.Name, it.attr.NameRange)
+			if child(m, key.Value) != nil {
+				return nil, hclErrorAt(it.attr.NameRange, "%q is already defined", key.Value)
+			}
+			value, err := hclExpr(it.attr.Expr)
+			if err != nil {
+				return nil, err
+			}
+			m.Content = append(m.Content, key, value)
+			continue
+		}
+
+		block := it.block
+		value, err := hclBody(block.Body)
+		if err != nil {
+			return nil, err
+		}
+		value.Line, value.Column = block.TypeRange.Start.Line, block.TypeRange.Start.Column
+
+		name, list := hclListBlocks[block.Type]
+		if !list {
+			name = block.Type
+		}
+		switch {
+		case len(block.Labels) > 1:
+			return nil, hclErrorAt(block.LabelRanges[1], "%s block has more than one label", block.Type)
+		case len(block.Labels) == 0 && !list:
+			if child(m, name) != nil {
+				return nil, hclErrorAt(block.TypeRange, "%q is already defined", name)
+			}
+			m.Content = append(m.Content, hclKey(name, block.TypeRange), value)
+			continue
+		}
+
+		kind := yaml.MappingNode
+		if len(block.Labels) == 0 {
+			kind = yaml.SequenceNode
+		}
+		group := groups[name]
+		switch {
+		case group == nil:
+			if child(m, name) != nil {
+				return nil, hclErrorAt(block.TypeRange, "%q is already defined", name)
+			}
+			group = mappingNode(value.Line, value.Column)
+			if kind == yaml.SequenceNode {
+				group = sequenceNode(value.Line, value.Column)
+			}
+			groups[name] = group
+			m.Content = append(m.Content, hclKey(name, block.TypeRange), group)
+		case group.Kind != kind:
+			return nil, hclErrorAt(block.TypeRange, "%s blocks must either all have a label or none", block.Type)
+		}
+
+		if kind == yaml.SequenceNode {
+			group.Content = append(group.Content, value)
+			continue
+		}
+		label := block.Labels[0]
+		if child(group, label) != nil {
+			return nil, hclErrorAt(block.LabelRanges[0], "%s block %q is already defined", block.Type, label)
+		}
+		group.Content = append(group.Content, hclKey(label, block.LabelRanges[0]), value)
+	}
+	return m, nil
+}
+
+// hclExpr converts the value of an expression to a node. Object and tuple
+// constructors are walked to keep the order and positions of their items.
+func hclExpr(expr hclsyntax.Expression) (*yaml.Node, error) {
+	start := expr.Range().Start
+	switch e := expr.(type) {
+	case *hclsyntax.ObjectConsExpr:
+		m := mappingNode(start.Line, start.Column)
+		for _, item := range e.Items {
+			k, diags := item.KeyExpr.Value(hclVars(item.KeyExpr))
+			if diags.HasErrors() {
+				return nil, hclError(diags)
+			}
+			if k.IsNull() || k.Type() != cty.String {
+				return nil, hclErrorAt(item.KeyExpr.Range(), "object keys must be strings")
+			}
+			key := hclKey(k.AsString(), item.KeyExpr.Range())
+			if child(m, key.Value) != nil {
+				return nil, hclErrorAt(item.KeyExpr.Range(), "%q is already defined", key.Value)
+			}
+			value, err := hclExpr(item.ValueExpr)
+			if err != nil {
+				return nil, err
+			}
+			m.Content = append(m.Content, key, value)
+		}
+		return m, nil
+	case *hclsyntax.TupleConsExpr:
+		seq := sequenceNode(start.Line, start.Column)
+		for _, item := range e.Exprs {
+			value, err := hclExpr(item)
+			if err != nil {
+				return nil, err
+			}
+			seq.Content = append(seq.Content, value)
+		}
+		return seq, nil
+	}
+
+	v, diags := expr.Value(hclVars(expr))
+	if diags.HasErrors() {
+		return nil, hclError(diags)
+	}
+	return ctyNode(v, expr.Range())
+}
+
+// hclVars returns the evaluation context of expr, in which every variable
+// it references evaluates to its own ${name} reference
+func hclVars(expr hcl.Expression) *hcl.EvalContext {
+	ctx := &hcl.EvalContext{Variables: make(map[string]cty.Value)}
+	for _, traversal := range expr.Variables() {
+		name := traversal.RootName()
+		ctx.Variables[name] = cty.StringVal("${" + name + "}")
+	}
+	return ctx
+}
+
+// ctyNode converts an evaluated value to a node at the start of rng
+func ctyNode(v cty.Value, rng hcl.Range) (*yaml.Node, error) {
+	line, column := rng.Start.Line, rng.Start.Column
+	if v.IsNull() {
+		return scalarNode("!!null", "null", line, column), nil
+	}
+
+	t := v.Type()
+	switch {
+	case t == cty.String:
+		return scalarNode("!!str", v.AsString(), line, column), nil
+	case t == cty.Bool:
+		return scalarNode("!!bool", strconv.FormatBool(v.True()), line, column), nil
+	case t == cty.Number:
+		f := v.AsBigFloat()
+		if f.IsInt() {
+			return scalarNode("!!int", f.Text('f', 0), line, column), nil
+		}
+		return scalarNode("!!float", f.Text('g', -1), line, column), nil
+	case t.IsListType() || t.IsTupleType() || t.IsSetType():
+		seq := sequenceNode(line, column)
+		for it := v.ElementIterator(); it.Next(); {
+			_, elem := it.Element()
+			item, err := ctyNode(elem, rng)
+			if err != nil {
+				return nil, err
+			}
+			seq.Content = append(seq.Content, item)
+		}
+		return seq, nil
+	case t.IsMapType() || t.IsObjectType():
+		m := mappingNode(line, column)
+		for it := v.ElementIterator(); it.Next(); {
+			k, elem := it.Element()
+			item, err := ctyNode(elem, rng)
+			if err != nil {
+				return nil, err
+			}
+			m.Content = append(m.Content, scalarNode("!!str", k.AsString(), line, column), item)
+		}
+		return m, nil
+	}
+	return nil, hclErrorAt(rng, "unsupported value of type %s", t.FriendlyName())
+}
+
+// hclKey returns the key node of name defined at rng
+func hclKey(name string, rng hcl.Range) *yaml.Node {
+	return scalarNode("!!str", name, rng.Start.Line, rng.Start.Column)
+}
+
+// hclError converts the errors of diagnostics to an error, one per line
+func hclError(diags hcl.Diagnostics) error {
+	var lines []string
+	for _, diag := range diags.Errs() {
+		d := diag.(*hcl.Diagnostic)
+		msg := d.Summary
+		if d.Detail != "" {
+			msg += "; " + d.Detail
+		}
+		if d.Subject == nil {
+			lines = append(lines, "hcl: "+msg)
+			continue
+		}
+		lines = append(lines, syntaxError("hcl", d.Subject.Start.Line, d.Subject.Start.Column, msg).Error())
+	}
+	return errors.New(strings.Join(lines, "\n"))
+}
+
+// hclErrorAt returns an error at the start of rng
+func hclErrorAt(rng hcl.Range, format string, args ...any) error {
+	return syntaxError("hcl", rng.Start.Line, rng.Start.Column, fmt.Sprintf(format, args...))
+}
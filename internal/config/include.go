@@ -0,0 +1,123 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// resolveIncludes merges the file c extends and the files it includes into
+// c. The base named by extends contributes its vars, config, policy and
+// sources; vars keys, config fields and the policy set in c take
+// precedence. Included files contribute only vars and sources. Sources are
+// ordered base first, then included files in order, then the sources of c.
+// Relative paths are resolved against dir, the directory of the file c was
+// loaded from, and stack holds the files being loaded to detect cycles.
+func (c *FileConfig) resolveIncludes(dir string, stack []string) error {
+	if c.Extends == "" && len(c.Include) == 0 {
+		return nil
+	}
+
+	vars := make(map[string]string)
+	var sources []Source
+
+	if c.Extends != "" {
+		base, err := loadInclude(dir, c.Extends, stack)
+		if err != nil {
+			return fmt.Errorf("extends %s: %w", c.Extends, err)
+		}
+		c.Config = base.Config.merge(c.Config)
+		if c.Policy == nil {
+			c.Policy = base.Policy
+		}
+		for name, value := range base.Vars {
+			vars[name] = value
+		}
+		sources = append(sources, base.Sources...)
+	}
+
+	for _, path := range c.Include {
+		included, err := loadInclude(dir, path, stack)
+		if err != nil {
+			return fmt.Errorf("include %s: %w", path, err)
+		}
+		if !reflect.ValueOf(included.Config).IsZero() || included.Policy != nil {
+			return fmt.Errorf("include %s: only vars and sources can be included, use extends to inherit config and policy", path)
+		}
+		for name, value := range included.Vars {
+			vars[name] = value
+		}
+		sources = append(sources, included.Sources...)
+	}
+
+	for name, value := range c.Vars {
+		vars[name] = value
+	}
+	if len(vars) > 0 {
+		c.Vars = vars
+	}
+	c.Sources = append(sources, c.Sources...)
+	return nil
+}
+
+// loadInclude reads the configuration file at path, relative to dir, and
+// resolves its own includes. Included files may be partial and are not
+// validated on their own.
+func loadInclude(dir, path string, stack []string) (*FileConfig, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if slices.Contains(stack, path) {
+		return nil, fmt.Errorf("include cycle: %s", strings.Join(append(stack, path), " -> "))
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var config FileConfig
+	if err := decode(data, path, FormatFor(path), &config); err != nil {
+		return nil, err
+	}
+	if err := config.resolveIncludes(filepath.Dir(path), append(slices.Clone(stack), path)); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// merge returns c with the fields set in o taking precedence
+func (c Config) merge(o Config) Config {
+	if o.Parallelism != 0 {
+		c.Parallelism = o.Parallelism
+	}
+	if o.Retries != 0 {
+		c.Retries = o.Retries
+	}
+	if o.Timeout != 0 {
+		c.Timeout = o.Timeout
+	}
+	if o.GoGetterPath != "" {
+		c.GoGetterPath = o.GoGetterPath
+	}
+	if o.CacheDir != "" {
+		c.CacheDir = o.CacheDir
+	}
+	if o.BaseDir != "" {
+		c.BaseDir = o.BaseDir
+	}
+	c.Backoff = c.Backoff.Merge(&o.Backoff)
+	if o.OutputRoot != "" {
+		c.OutputRoot = o.OutputRoot
+	}
+	if o.Getters != nil {
+		c.Getters = o.Getters
+	}
+	return c
+}
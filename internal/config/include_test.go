@@ -0,0 +1,145 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+	}
+}
+
+func TestLoadConfigIncludes(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{
+		"shared/base.yaml": `vars:
+  host: "example.com"
+  version: "v1"
+config:
+  parallelism: 2
+  timeout: 10s
+  backoff:
+    initial: 2s
+policy:
+  allowed-hosts: ["example.com"]
+sources:
+  - url: "https://${host}/base.txt"
+    dest: "base.txt"
+`,
+		"shared/common.yaml": `include:
+  - nested/tools.yaml
+sources:
+  - url: "https://${host}/common.txt"
+    dest: "common.txt"
+`,
+		"shared/nested/tools.yaml": `vars:
+  version: "v2"
+sources:
+  - url: "https://${host}/${version}/tools.txt"
+    dest: "tools.txt"
+`,
+		"app.go.getter.yaml": `version: 1
+name: "app"
+extends: shared/base.yaml
+include:
+  - shared/common.yaml
+vars:
+  version: "v3"
+config:
+  timeout: 20s
+sources:
+  - url: "https://${host}/app.txt"
+    dest: "app.txt"
+`,
+	})
+
+	cfg, err := LoadConfig(filepath.Join(tmpDir, "app.go.getter.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfig() unexpected error: %v", err)
+	}
+
+	wantURLs := []string{
+		"https://example.com/base.txt",
+		"https://example.com/v3/tools.txt",
+		"https://example.com/common.txt",
+		"https://example.com/app.txt",
+	}
+	if len(cfg.Sources) != len(wantURLs) {
+		t.Fatalf("got %d sources, want %d", len(cfg.Sources), len(wantURLs))
+	}
+	for i, want := range wantURLs {
+		if cfg.Sources[i].URL != want {
+			t.Errorf("Sources[%d].URL = %s, want %s", i, cfg.Sources[i].URL, want)
+		}
+	}
+
+	if cfg.Config.Parallelism != 2 {
+		t.Errorf("Parallelism = %d, want 2 from the base", cfg.Config.Parallelism)
+	}
+	if cfg.Config.Timeout != 20*time.Second {
+		t.Errorf("Timeout = %v, want 20s from the extending file", cfg.Config.Timeout)
+	}
+	if initial, maxWait, _, _ := cfg.Config.Backoff.Values(); initial != 2*time.Second || maxWait != DefaultBackoffMax {
+		t.Errorf("Backoff = %v, want initial from the base and defaults elsewhere", fmt.Sprint(cfg.Config.Backoff.Values()))
+	}
+	if cfg.Policy == nil || cfg.Policy.AllowedHosts[0] != "example.com" {
+		t.Errorf("Policy = %+v, want the policy of the base", cfg.Policy)
+	}
+}
+
+func TestLoadConfigIncludeErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     map[string]string
+		wantError string
+	}{
+		{
+			name: "cycle",
+			files: map[string]string{
+				"main.yaml": "version: 1\nname: \"main\"\ninclude: [b.yaml]\n",
+				"b.yaml":    "extends: main.yaml\n",
+			},
+			wantError: "include b.yaml: extends main.yaml: include cycle: ",
+		},
+		{
+			name: "missing file",
+			files: map[string]string{
+				"main.yaml": "version: 1\nname: \"main\"\nextends: missing.yaml\n",
+			},
+			wantError: "extends missing.yaml: open ",
+		},
+		{
+			name: "config in included file",
+			files: map[string]string{
+				"main.yaml":  "version: 1\nname: \"main\"\ninclude: [other.yaml]\n",
+				"other.yaml": "config:\n  retries: 5\n",
+			},
+			wantError: "include other.yaml: only vars and sources can be included",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			writeFiles(t, tmpDir, tt.files)
+
+			_, err := LoadConfig(filepath.Join(tmpDir, "main.yaml"))
+			if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("LoadConfig() error = %v, want error containing %q", err, tt.wantError)
+			}
+		})
+	}
+}
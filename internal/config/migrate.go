@@ -0,0 +1,136 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrAlreadyMigrated is returned by Migrate for files already in the latest
+// format version
+var ErrAlreadyMigrated = errors.New("already at the latest version")
+
+// unsafeNameChars matches the characters replaced in generated source names
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// Migrate rewrites a version 1 configuration file to version 2. The YAML
+// node tree is edited in place so comments are kept: sources become a
+// mapping keyed by a name derived from their dest, and their options move
+// under options. Files that set no version, like included files, are
+// migrated without adding one.
+func Migrate(data []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	root := docRoot(&doc)
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("not a configuration file")
+	}
+
+	version, err := detectVersion(&doc)
+	if err != nil {
+		return nil, err
+	}
+	if version == LatestVersion {
+		return nil, ErrAlreadyMigrated
+	}
+	if node := child(root, "version"); node != nil {
+		node.Value = fmt.Sprint(LatestVersion)
+	}
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "sources" || root.Content[i+1].Kind != yaml.SequenceNode {
+			continue
+		}
+		sources, err := migrateSources(root.Content[i+1])
+		if err != nil {
+			return nil, err
+		}
+		root.Content[i+1] = sources
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// migrateSources turns a version 1 sources sequence into a version 2
+// mapping. Comments of an item move to the key of its source.
+func migrateSources(seq *yaml.Node) (*yaml.Node, error) {
+	sources := &yaml.Node{
+		Kind:        yaml.MappingNode,
+		Tag:         "!!map",
+		HeadComment: seq.HeadComment,
+		LineComment: seq.LineComment,
+		FootComment: seq.FootComment,
+	}
+	used := make(map[string]bool)
+
+	for i, item := range seq.Content {
+		if item.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("source %d: expected a mapping", i)
+		}
+
+		name := sourceName(item, i, used)
+		key := &yaml.Node{
+			Kind:        yaml.ScalarNode,
+			Tag:         "!!str",
+			Value:       name,
+			HeadComment: item.HeadComment,
+			LineComment: item.LineComment,
+			FootComment: item.FootComment,
+		}
+		source := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		options := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for j := 0; j+1 < len(item.Content); j += 2 {
+			switch item.Content[j].Value {
+			case "url", "dest":
+				source.Content = append(source.Content, item.Content[j], item.Content[j+1])
+			default:
+				options.Content = append(options.Content, item.Content[j], item.Content[j+1])
+			}
+		}
+		if len(options.Content) > 0 {
+			source.Content = append(source.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "options"}, options)
+		}
+		sources.Content = append(sources.Content, key, source)
+	}
+	return sources, nil
+}
+
+// sourceName derives a unique name for the i-th source from the base name
+// of its dest without extension and variable references, falling back to
+// source-<n>
+func sourceName(item *yaml.Node, i int, used map[string]bool) string {
+	var name string
+	if dest := child(item, "dest"); dest != nil {
+		base := varPattern.ReplaceAllString(dest.Value, "")
+		base = path.Base(strings.ReplaceAll(base, `\`, "/"))
+		base = strings.TrimSuffix(base, path.Ext(base))
+		name = strings.Trim(unsafeNameChars.ReplaceAllString(base, "-"), "-.")
+	}
+	if name == "" {
+		name = fmt.Sprintf("source-%d", i+1)
+	}
+
+	unique := name
+	for n := 2; used[unique]; n++ {
+		unique = fmt.Sprintf("%s-%d", name, n)
+	}
+	used[unique] = true
+	return unique
+}
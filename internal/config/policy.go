@@ -0,0 +1,187 @@
+package config
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// PolicyEnv is the environment variable naming the global policy file when
+// --policy is not given
+const PolicyEnv = "GO_GETTER_FILE_POLICY"
+
+// ErrPolicyDenied is returned for sources whose URL a policy does not allow
+var ErrPolicyDenied = errors.New("denied by policy")
+
+// forcedPattern matches a forced getter prefix like git:: in a URL
+var forcedPattern = regexp.MustCompile(`^([A-Za-z0-9]+)::(.+)$`)
+
+// Policy restricts the origins sources may be fetched from. Every list that
+// is set has to be satisfied. Hosts match exactly or, written as
+// *.example.com, any subdomain. Patterns are regular expressions matched
+// against the URL without a forced getter prefix such as git::.
+type Policy struct {
+	AllowedSchemes  []string `yaml:"allowed-schemes,omitempty"`
+	AllowedHosts    []string `yaml:"allowed-hosts,omitempty"`
+	DeniedHosts     []string `yaml:"denied-hosts,omitempty"`
+	AllowedPatterns []string `yaml:"allowed-patterns,omitempty"`
+	DeniedPatterns  []string `yaml:"denied-patterns,omitempty"`
+}
+
+// LoadPolicy loads and validates a policy file
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file %s: %w", path, err)
+	}
+
+	var policy Policy
+	doc, err := FormatFor(path).parse(data, path)
+	if err == nil {
+		err = decodeStrict(doc, path, &policy)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse policy file %s: %w", path, err)
+	}
+	if err := policy.validate(); err != nil {
+		return nil, fmt.Errorf("invalid policy file %s: %w", path, err)
+	}
+	return &policy, nil
+}
+
+// validate checks that the policy has no empty entries and that its
+// patterns compile
+func (p *Policy) validate() error {
+	if p == nil {
+		return nil
+	}
+
+	lists := []struct {
+		name    string
+		entries []string
+	}{
+		{"allowed-schemes", p.AllowedSchemes},
+		{"allowed-hosts", p.AllowedHosts},
+		{"denied-hosts", p.DeniedHosts},
+		{"allowed-patterns", p.AllowedPatterns},
+		{"denied-patterns", p.DeniedPatterns},
+	}
+	for _, list := range lists {
+		for _, entry := range list.entries {
+			if entry == "" {
+				return fmt.Errorf("%s entries must not be empty", list.name)
+			}
+		}
+	}
+
+	for _, pattern := range slices.Concat(p.AllowedPatterns, p.DeniedPatterns) {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Check returns an error wrapping ErrPolicyDenied when the policy does not
+// allow rawURL. The scheme of a URL without one is its forced getter, like
+// file for file::/path, or file for an absolute path. A nil policy allows
+// every URL.
+func (p *Policy) Check(rawURL string) error {
+	if p == nil {
+		return nil
+	}
+
+	src, forced := rawURL, ""
+	if m := forcedPattern.FindStringSubmatch(rawURL); m != nil {
+		src, forced = m[2], m[1]
+	}
+	u, err := url.Parse(src)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrPolicyDenied, rawURL, err)
+	}
+	scheme := strings.ToLower(cmp.Or(u.Scheme, forced))
+	if scheme == "" && filepath.IsAbs(src) {
+		scheme = "file"
+	}
+	host := strings.ToLower(u.Hostname())
+
+	if len(p.AllowedSchemes) > 0 && !slices.ContainsFunc(p.AllowedSchemes, func(s string) bool {
+		return strings.EqualFold(s, scheme)
+	}) {
+		return fmt.Errorf("%w: %s: scheme %q is not allowed", ErrPolicyDenied, rawURL, scheme)
+	}
+	if matchHost(p.DeniedHosts, host) {
+		return fmt.Errorf("%w: %s: host %q is denied", ErrPolicyDenied, rawURL, host)
+	}
+	if len(p.AllowedHosts) > 0 && !matchHost(p.AllowedHosts, host) {
+		return fmt.Errorf("%w: %s: host %q is not allowed", ErrPolicyDenied, rawURL, host)
+	}
+
+	for _, pattern := range p.DeniedPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("%w: invalid pattern %q: %v", ErrPolicyDenied, pattern, err)
+		}
+		if re.MatchString(src) {
+			return fmt.Errorf("%w: %s: matches denied pattern %q", ErrPolicyDenied, rawURL, pattern)
+		}
+	}
+	if len(p.AllowedPatterns) == 0 {
+		return nil
+	}
+	for _, pattern := range p.AllowedPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("%w: invalid pattern %q: %v", ErrPolicyDenied, pattern, err)
+		}
+		if re.MatchString(src) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s: does not match an allowed pattern", ErrPolicyDenied, rawURL)
+}
+
+// matchHost reports whether host matches one of the entries
+func matchHost(entries []string, host string) bool {
+	for _, entry := range entries {
+		entry = strings.ToLower(entry)
+		if suffix, ok := strings.CutPrefix(entry, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+			continue
+		}
+		if host == entry {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPolicy checks the URL of every source against p and adds the denied
+// ones to errs. Shorthand URLs without a scheme, like github.com/org/repo,
+// are skipped; the fetcher checks them once go-getter detection has turned
+// them into full URLs. Absolute paths are checked as file URLs.
+func (c *FileConfig) checkPolicy(p *Policy, errs *problems) {
+	if p == nil {
+		return
+	}
+	for i, source := range c.Sources {
+		src := source.URL
+		if m := forcedPattern.FindStringSubmatch(src); m != nil {
+			src = m[2]
+		}
+		if !strings.Contains(src, "://") && !filepath.IsAbs(src) {
+			continue
+		}
+		if err := p.Check(source.URL); err != nil {
+			errs.add(source.origin, fmt.Errorf("%s: %w", source.label(i), err), "url")
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	policy := &Policy{
+		AllowedSchemes:  []string{"https", "git"},
+		AllowedHosts:    []string{"github.com", "*.example.com"},
+		DeniedHosts:     []string{"evil.example.com"},
+		AllowedPatterns: []string{`^https://github\.com/acme/`, `\.example\.com/`},
+		DeniedPatterns:  []string{`/private/`},
+	}
+
+	tests := []struct {
+		name    string
+		url     string
+		wantErr string
+	}{
+		{name: "allowed repository", url: "https://github.com/acme/tools.git"},
+		{name: "forced getter prefix", url: "git::https://github.com/acme/tools.git"},
+		{name: "allowed subdomain", url: "https://cdn.example.com/file.txt"},
+		{name: "scheme not allowed", url: "http://github.com/acme/tools", wantErr: `scheme "http" is not allowed`},
+		{name: "host not allowed", url: "https://gitlab.com/acme/tools", wantErr: `host "gitlab.com" is not allowed`},
+		{name: "apex of wildcard", url: "https://example.com/file.txt", wantErr: `host "example.com" is not allowed`},
+		{name: "denied host", url: "https://evil.example.com/file.txt", wantErr: `host "evil.example.com" is denied`},
+		{name: "denied pattern", url: "https://github.com/acme/private/repo", wantErr: "matches denied pattern"},
+		{name: "no allowed pattern", url: "https://github.com/other/repo", wantErr: "does not match an allowed pattern"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Check(tt.url)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Check(%q) unexpected error: %v", tt.url, err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrPolicyDenied) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Check(%q) error = %v, want ErrPolicyDenied containing %q", tt.url, err, tt.wantErr)
+			}
+		})
+	}
+
+	// Local paths have no URL scheme, they are checked as file URLs
+	https := &Policy{AllowedSchemes: []string{"https"}}
+	for _, src := range []string{"file::/etc/passwd", "/etc/passwd", "file:///etc/passwd"} {
+		if err := https.Check(src); !errors.Is(err, ErrPolicyDenied) || !strings.Contains(err.Error(), `scheme "file" is not allowed`) {
+			t.Errorf("Check(%q) error = %v, want the file scheme denied", src, err)
+		}
+	}
+	if err := (&Policy{AllowedSchemes: []string{"file"}}).Check("file::/srv/data"); err != nil {
+		t.Errorf("Check() with the file scheme allowed unexpected error: %v", err)
+	}
+
+	var none *Policy
+	if err := none.Check("http://anything"); err != nil {
+		t.Errorf("nil policy Check() unexpected error: %v", err)
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	valid := filepath.Join(tmpDir, "policy.yaml")
+	if err := os.WriteFile(valid, []byte("allowed-hosts: [\"github.com\"]\n"), 0644); err != nil {
+		t.Fatalf("Failed to create policy file: %v", err)
+	}
+	policy, err := LoadPolicy(valid)
+	if err != nil {
+		t.Fatalf("LoadPolicy() unexpected error: %v", err)
+	}
+	if len(policy.AllowedHosts) != 1 || policy.AllowedHosts[0] != "github.com" {
+		t.Errorf("AllowedHosts = %v, want [github.com]", policy.AllowedHosts)
+	}
+
+	invalid := filepath.Join(tmpDir, "invalid.yaml")
+	if err := os.WriteFile(invalid, []byte("allowed-patterns: [\"(\"]\n"), 0644); err != nil {
+		t.Fatalf("Failed to create policy file: %v", err)
+	}
+	if _, err := LoadPolicy(invalid); err == nil || !strings.Contains(err.Error(), "invalid pattern") {
+		t.Errorf("LoadPolicy() error = %v, want an invalid pattern error", err)
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	data := []byte(`version: 1
+name: "test"
+policy:
+  allowed-hosts: ["github.com"]
+sources:
+  - url: "github.com/acme/tools"
+    dest: "tools"
+  - url: "https://example.com/file.txt"
+    dest: "file.txt"
+`)
+
+	_, err := Parse(data)
+	if !errors.Is(err, ErrPolicyDenied) || !strings.Contains(err.Error(), "source 1:") {
+		t.Fatalf("Parse() error = %v, want source 1 denied by the config policy", err)
+	}
+
+	global := &Policy{AllowedSchemes: []string{"ssh"}}
+	data = []byte(`version: 1
+name: "test"
+sources:
+  - url: "https://example.com/file.txt"
+    dest: "file.txt"
+`)
+	if _, err := Parse(data); err != nil {
+		t.Fatalf("Parse() without policy unexpected error: %v", err)
+	}
+	if _, err := Parse(data, WithPolicy(global)); !errors.Is(err, ErrPolicyDenied) {
+		t.Errorf("Parse() error = %v, want it denied by the global policy", err)
+	}
+}
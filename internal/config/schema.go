@@ -0,0 +1,114 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// SchemaID is the $schema dialect of the generated JSON Schema
+const SchemaID = "https://json-schema.org/draft/2020-12/schema"
+
+// durationPattern matches the Go duration strings accepted by time.ParseDuration
+const durationPattern = `^[-+]?((([0-9]+(\.[0-9]*)?|\.[0-9]+)(ns|us|µs|μs|ms|s|m|h))+|0)$`
+
+// schemaEnums lists the allowed values of fields validated against a fixed
+// set, keyed by type and yaml name
+var schemaEnums = map[string][]any{
+	"Source.mode":          {ModeAny, ModeFile, ModeDir},
+	"sourceOptionsV2.mode": {ModeAny, ModeFile, ModeDir},
+}
+
+// JSONSchema returns a JSON Schema for configuration files of every format
+// version, generated from the yaml tags of the types they are decoded into.
+// Fields without omitempty are required, except for sections, which may be
+// inherited with extends and include. Unknown fields are rejected like when
+// a configuration is loaded.
+func JSONSchema() map[string]any {
+	versions := []struct {
+		version int
+		typ     reflect.Type
+	}{
+		{Version1, reflect.TypeOf(FileConfig{})},
+		{Version2, reflect.TypeOf(fileConfigV2{})},
+	}
+
+	var oneOf []any
+	for _, v := range versions {
+		schema := schemaFor(v.typ)
+		schema["title"] = fmt.Sprintf("version %d", v.version)
+		schema["properties"].(map[string]any)["version"] = map[string]any{"const": v.version}
+		oneOf = append(oneOf, schema)
+	}
+	return map[string]any{
+		"$schema": SchemaID,
+		"title":   "go-getter-file configuration",
+		"oneOf":   oneOf,
+	}
+}
+
+// schemaFor returns the schema of values of type t
+func schemaFor(t reflect.Type) map[string]any {
+	if t == reflect.TypeOf(time.Duration(0)) {
+		return map[string]any{"type": "string", "pattern": durationPattern}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return schemaFor(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaFor(t.Elem())}
+	case reflect.Struct:
+		return structSchema(t)
+	}
+	panic("config: no JSON Schema for type " + t.String())
+}
+
+// structSchema returns the schema of a struct with yaml tags
+func structSchema(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	var required []string
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		prop := schemaFor(field.Type)
+		if enum, ok := schemaEnums[t.Name()+"."+name]; ok {
+			prop["enum"] = enum
+		}
+		properties[name] = prop
+
+		kind := field.Type.Kind()
+		if opts != "omitempty" && kind != reflect.Struct && kind != reflect.Slice {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
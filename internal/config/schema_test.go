@@ -0,0 +1,75 @@
+package config
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestJSONSchema(t *testing.T) {
+	root := JSONSchema()
+	if root["$schema"] != SchemaID {
+		t.Errorf("$schema = %v, want %s", root["$schema"], SchemaID)
+	}
+	versions := root["oneOf"].([]any)
+	if len(versions) != 2 {
+		t.Fatalf("oneOf has %d schemas, want one per version", len(versions))
+	}
+
+	schema := versions[0].(map[string]any)
+	if schema["additionalProperties"] != false {
+		t.Error("additionalProperties is not false, want unknown fields rejected")
+	}
+	if required := schema["required"].([]string); !slices.Equal(required, []string{"version", "name"}) {
+		t.Errorf("required = %v, want [version name]", required)
+	}
+
+	properties := schema["properties"].(map[string]any)
+	if version := properties["version"].(map[string]any)["const"]; version != Version1 {
+		t.Errorf("version const = %v, want %d", version, Version1)
+	}
+	for _, name := range []string{"extends", "include", "vars", "config", "policy", "sources"} {
+		if _, ok := properties[name]; !ok {
+			t.Errorf("property %q missing", name)
+		}
+	}
+
+	source := properties["sources"].(map[string]any)["items"].(map[string]any)
+	if required := source["required"].([]string); !slices.Equal(required, []string{"url", "dest"}) {
+		t.Errorf("source required = %v, want [url dest]", required)
+	}
+	sourceProps := source["properties"].(map[string]any)
+	if enum := sourceProps["mode"].(map[string]any)["enum"]; !slices.Equal(enum.([]any), []any{ModeAny, ModeFile, ModeDir}) {
+		t.Errorf("mode enum = %v", enum)
+	}
+	if typ := sourceProps["recursive"].(map[string]any)["type"]; typ != "boolean" {
+		t.Errorf("recursive type = %v, want boolean", typ)
+	}
+	if typ := sourceProps["timeout"].(map[string]any)["type"]; typ != "string" {
+		t.Errorf("timeout type = %v, want string", typ)
+	}
+
+	v2 := versions[1].(map[string]any)["properties"].(map[string]any)
+	sourcesV2 := v2["sources"].(map[string]any)
+	if sourcesV2["type"] != "object" {
+		t.Errorf("version 2 sources type = %v, want sources keyed by name", sourcesV2["type"])
+	}
+	optionsV2 := sourcesV2["additionalProperties"].(map[string]any)["properties"].(map[string]any)["options"].(map[string]any)
+	if _, ok := optionsV2["properties"].(map[string]any)["checksum"]; !ok {
+		t.Error("version 2 source options have no checksum")
+	}
+	if _, ok := v2["hooks"]; !ok {
+		t.Error("version 2 has no hooks")
+	}
+}
+
+func TestDurationPattern(t *testing.T) {
+	re := regexp.MustCompile(durationPattern)
+	for _, s := range []string{"30s", "1m30s", "1.5h", ".5s", "0", "-2ms", "300µs", "10x", "", "s", "1 m"} {
+		_, err := time.ParseDuration(s)
+		if got, want := re.MatchString(s), err == nil; got != want {
+			t.Errorf("pattern matches %q = %v, time.ParseDuration accepts it = %v", s, got, want)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2/unstable"
+	"gopkg.in/yaml.v3"
+)
+
+// tomlDoc builds the YAML node tree of a TOML document from its
+// expressions, keeping the order and positions of its keys
+type tomlDoc struct {
+	parser *unstable.Parser
+	root   *yaml.Node
+	// tables are the tables defined by a [table] header
+	tables map[*yaml.Node]bool
+	// sealed are the arrays and inline tables, which cannot be extended
+	sealed map[*yaml.Node]bool
+}
+
+// parseTOML parses a TOML document. It walks the expressions of the
+// unstable parser of go-toml, the only API of the library that exposes the
+// order and positions of keys: toml.Unmarshal decodes into maps, which
+// loses the order of version 2 sources and the line and column of every
+// problem. The unstable API has no compatibility promise, so the go-toml
+// version is pinned in go.mod and an upgrade has to pass the format tests.
+func parseTOML(data []byte, _ string) (*yaml.Node, error) {
+	p := &unstable.Parser{}
+	p.Reset(data)
+	t := &tomlDoc{
+		parser: p,
+		root:   mappingNode(1, 1),
+		tables: make(map[*yaml.Node]bool),
+		sealed: make(map[*yaml.Node]bool),
+	}
+
+	current := t.root
+	for p.NextExpression() {
+		var err error
+		switch expr := p.Expression(); expr.Kind {
+		case unstable.KeyValue:
+			err = t.setValue(current, expr)
+		case unstable.Table:
+			current, err = t.table(expr)
+		case unstable.ArrayTable:
+			current, err = t.arrayTable(expr)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := p.Error(); err != nil {
+		var parserErr *unstable.ParserError
+		if errors.As(err, &parserErr) && len(parserErr.Highlight) > 0 {
+			pos := p.Shape(p.Range(parserErr.Highlight)).Start
+			return nil, syntaxError("toml", pos.Line, pos.Column, parserErr.Message)
+		}
+		return nil, fmt.Errorf("toml: %w", err)
+	}
+	return documentNode(t.root), nil
+}
+
+// keys returns the parts of a dotted key as key nodes
+func (t *tomlDoc) keys(it unstable.Iterator) []*yaml.Node {
+	var keys []*yaml.Node
+	for it.Next() {
+		n := it.Node()
+		pos := t.parser.Shape(n.Raw).Start
+		keys = append(keys, scalarNode("!!str", string(n.Data), pos.Line, pos.Column))
+	}
+	return keys
+}
+
+// descend returns the table at keys below table, creating missing tables.
+// Keys naming an array of tables descend into its last table.
+func (t *tomlDoc) descend(table *yaml.Node, keys []*yaml.Node) (*yaml.Node, error) {
+	for _, key := range keys {
+		next := child(table, key.Value)
+		switch {
+		case next == nil:
+			next = mappingNode(key.Line, key.Column)
+			table.Content = append(table.Content, key, next)
+		case t.sealed[next]:
+			return nil, t.errorAt(key, "key %q is already defined", key.Value)
+		case next.Kind == yaml.SequenceNode:
+			next = next.Content[len(next.Content)-1]
+		case next.Kind != yaml.MappingNode:
+			return nil, t.errorAt(key, "key %q is already defined", key.Value)
+		}
+		table = next
+	}
+	return table, nil
+}
+
+// table handles a [table] header and returns the table it opens
+func (t *tomlDoc) table(expr *unstable.Node) (*yaml.Node, error) {
+	keys := t.keys(expr.Key())
+	parent, err := t.descend(t.root, keys[:len(keys)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	key := keys[len(keys)-1]
+	table := child(parent, key.Value)
+	switch {
+	case table == nil:
+		table = mappingNode(key.Line, key.Column)
+		parent.Content = append(parent.Content, key, table)
+	case table.Kind != yaml.MappingNode || t.tables[table] || t.sealed[table]:
+		return nil, t.errorAt(key, "table %q is already defined", key.Value)
+	}
+	t.tables[table] = true
+	return table, nil
+}
+
+// arrayTable handles a [[table]] header and returns the table it appends
+// to the array of tables
+func (t *tomlDoc) arrayTable(expr *unstable.Node) (*yaml.Node, error) {
+	keys := t.keys(expr.Key())
+	parent, err := t.descend(t.root, keys[:len(keys)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	key := keys[len(keys)-1]
+	array := child(parent, key.Value)
+	switch {
+	case array == nil:
+		array = sequenceNode(key.Line, key.Column)
+		parent.Content = append(parent.Content, key, array)
+	case array.Kind != yaml.SequenceNode || t.sealed[array]:
+		return nil, t.errorAt(key, "key %q is already defined", key.Value)
+	}
+	table := mappingNode(key.Line, key.Column)
+	array.Content = append(array.Content, table)
+	return table, nil
+}
+
+// setValue sets the value of a key = value expression in table
+func (t *tomlDoc) setValue(table *yaml.Node, expr *unstable.Node) error {
+	keys := t.keys(expr.Key())
+	table, err := t.descend(table, keys[:len(keys)-1])
+	if err != nil {
+		return err
+	}
+
+	key := keys[len(keys)-1]
+	if child(table, key.Value) != nil {
+		return t.errorAt(key, "key %q is already defined", key.Value)
+	}
+	value, err := t.value(expr.Value(), key)
+	if err != nil {
+		return err
+	}
+	table.Content = append(table.Content, key, value)
+	return nil
+}
+
+// value converts a TOML value to a node. Values without a position of their
+// own, like arrays, get the position of key.
+func (t *tomlDoc) value(n *unstable.Node, key *yaml.Node) (*yaml.Node, error) {
+	line, column := key.Line, key.Column
+	if n.Raw.Length > 0 {
+		pos := t.parser.Shape(n.Raw).Start
+		line, column = pos.Line, pos.Column
+	}
+	data := string(n.Data)
+
+	switch n.Kind {
+	case unstable.String:
+		return scalarNode("!!str", data, line, column), nil
+	case unstable.Bool:
+		return scalarNode("!!bool", data, line, column), nil
+	case unstable.Integer:
+		i, err := strconv.ParseInt(data, 0, 64)
+		if err != nil {
+			return nil, syntaxError("toml", line, column, fmt.Sprintf("invalid integer %s", data))
+		}
+		return scalarNode("!!int", strconv.FormatInt(i, 10), line, column), nil
+	case unstable.Float:
+		f, err := strconv.ParseFloat(strings.ReplaceAll(data, "_", ""), 64)
+		if err != nil {
+			return nil, syntaxError("toml", line, column, fmt.Sprintf("invalid float %s", data))
+		}
+		value := strconv.FormatFloat(f, 'g', -1, 64)
+		switch {
+		case math.IsNaN(f):
+			value = ".nan"
+		case math.IsInf(f, 1):
+			value = ".inf"
+		case math.IsInf(f, -1):
+			value = "-.inf"
+		}
+		return scalarNode("!!float", value, line, column), nil
+	case unstable.LocalDate, unstable.LocalTime, unstable.LocalDateTime, unstable.DateTime:
+		return scalarNode("!!str", data, line, column), nil
+	case unstable.Array:
+		array := sequenceNode(line, column)
+		for it := n.Children(); it.Next(); {
+			if it.Node().Kind == unstable.Comment {
+				continue
+			}
+			item, err := t.value(it.Node(), array)
+			if err != nil {
+				return nil, err
+			}
+			array.Content = append(array.Content, item)
+		}
+		t.sealed[array] = true
+		return array, nil
+	case unstable.InlineTable:
+		table := mappingNode(line, column)
+		for it := n.Children(); it.Next(); {
+			if it.Node().Kind != unstable.KeyValue {
+				continue
+			}
+			if err := t.setValue(table, it.Node()); err != nil {
+				return nil, err
+			}
+		}
+		t.sealed[table] = true
+		return table, nil
+	}
+	return nil, syntaxError("toml", line, column, fmt.Sprintf("unsupported value %s", n.Kind))
+}
+
+// errorAt returns an error at the position of key
+func (t *tomlDoc) errorAt(key *yaml.Node, format string, args ...any) error {
+	return syntaxError("toml", key.Line, key.Column, fmt.Sprintf(format, args...))
+}
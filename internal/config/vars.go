@@ -0,0 +1,107 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// varPattern matches ${name}, ${env:NAME} and the $${ escape
+var varPattern = regexp.MustCompile(`\$\$\{|\$\{([^}]*)\}`)
+
+// LoadOption configures how a configuration file is loaded
+type LoadOption func(*loadOptions)
+
+type loadOptions struct {
+	vars   map[string]string
+	policy *Policy
+	format string
+}
+
+// WithVars sets variables that take precedence over the vars section of
+// the configuration file
+func WithVars(vars map[string]string) LoadOption {
+	return func(o *loadOptions) {
+		o.vars = vars
+	}
+}
+
+// WithPolicy checks the sources against a policy, typically the global
+// one, in addition to the policy section of the configuration file
+func WithPolicy(p *Policy) LoadOption {
+	return func(o *loadOptions) {
+		o.policy = p
+	}
+}
+
+// interpolate resolves ${name} and ${env:NAME} references in the url and
+// dest of every source. Values of the vars section may only reference
+// environment variables.
+func (c *FileConfig) interpolate(overrides map[string]string) error {
+	var p problems
+	vars := make(map[string]string, len(c.Vars)+len(overrides))
+	for _, name := range slices.Sorted(maps.Keys(c.Vars)) {
+		expanded, err := expand(c.Vars[name], nil)
+		if err != nil {
+			p.add(c.origin, fmt.Errorf("vars.%s: %w", name, err), "vars", name)
+			continue
+		}
+		vars[name] = expanded
+	}
+	for name, value := range overrides {
+		vars[name] = value
+	}
+	if err := p.err(); err != nil {
+		return err
+	}
+
+	for i := range c.Sources {
+		src := &c.Sources[i]
+
+		url, err := expand(src.URL, vars)
+		if err != nil {
+			p.add(src.origin, fmt.Errorf("%s: url: %w", src.label(i), err), "url")
+		}
+		dest, err := expand(src.Dest, vars)
+		if err != nil {
+			p.add(src.origin, fmt.Errorf("%s: dest: %w", src.label(i), err), "dest")
+		}
+
+		src.URL = url
+		src.Dest = dest
+	}
+
+	return p.err()
+}
+
+// expand replaces variable references in s. With vars set to nil only
+// environment references are allowed.
+func expand(s string, vars map[string]string) (string, error) {
+	var firstErr error
+
+	result := varPattern.ReplaceAllStringFunc(s, func(match string) string {
+		if match == "$${" {
+			return "${"
+		}
+
+		name := match[2 : len(match)-1]
+		if env, ok := strings.CutPrefix(name, "env:"); ok {
+			value, ok := os.LookupEnv(env)
+			if !ok && firstErr == nil {
+				firstErr = fmt.Errorf("environment variable %q is not set", env)
+			}
+			return value
+		}
+
+		value, ok := vars[name]
+		if !ok && firstErr == nil {
+			firstErr = fmt.Errorf("undefined variable %q", name)
+		}
+		return value
+	})
+
+	return result, firstErr
+}
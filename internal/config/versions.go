@@ -0,0 +1,204 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Configuration file format versions
+const (
+	Version1 = 1
+	Version2 = 2
+	// LatestVersion is the version migrate rewrites files to
+	LatestVersion = Version2
+)
+
+// decoders maps each file format version to the function decoding it into
+// the FileConfig every version is normalized to
+var decoders = map[int]func(doc *yaml.Node, file string, config *FileConfig) error{
+	Version1: decodeV1,
+	Version2: decodeV2,
+}
+
+// CommandHooks are shell commands run around the fetch of a source, in the
+// directory of the configuration file. They see the source in the
+// GO_GETTER_FILE_SOURCE_NAME, GO_GETTER_FILE_SOURCE_URL and
+// GO_GETTER_FILE_SOURCE_DEST environment variables.
+type CommandHooks struct {
+	// Before runs before the fetch; a failing command fails the source
+	// without fetching it
+	Before []string `yaml:"before,omitempty"`
+	// After runs after a successful fetch; a failing command fails the source
+	After []string `yaml:"after,omitempty"`
+}
+
+// fileConfigV2 is the version 2 file format. Sources are keyed by name,
+// their options are grouped under options, and hooks may be set for every
+// source and per source.
+type fileConfigV2 struct {
+	Version int                 `yaml:"version"`
+	Name    string              `yaml:"name"`
+	Extends string              `yaml:"extends,omitempty"`
+	Include []string            `yaml:"include,omitempty"`
+	Vars    map[string]string   `yaml:"vars,omitempty"`
+	Config  Config              `yaml:"config"`
+	Policy  *Policy             `yaml:"policy,omitempty"`
+	Hooks   CommandHooks        `yaml:"hooks,omitempty"`
+	Sources map[string]sourceV2 `yaml:"sources"`
+}
+
+// sourceV2 is a source of the version 2 file format
+type sourceV2 struct {
+	URL     string          `yaml:"url"`
+	Dest    string          `yaml:"dest"`
+	Options sourceOptionsV2 `yaml:"options,omitempty"`
+	Hooks   CommandHooks    `yaml:"hooks,omitempty"`
+}
+
+// sourceOptionsV2 holds the optional settings of a version 2 source, which
+// version 1 sets next to url and dest
+type sourceOptionsV2 struct {
+	Timeout      time.Duration `yaml:"timeout,omitempty"`
+	Retries      *int          `yaml:"retries,omitempty"`
+	Backoff      *Backoff      `yaml:"backoff,omitempty"`
+	GoGetterPath *string       `yaml:"go-getter-path,omitempty"`
+	Mode         string        `yaml:"mode,omitempty"`
+	Weight       int           `yaml:"weight,omitempty"`
+	Recursive    *bool         `yaml:"recursive,omitempty"`
+	Checksum     string        `yaml:"checksum,omitempty"`
+}
+
+// decode decodes data read from file in format into config with the
+// decoder of its format version. Unknown fields are rejected and the nodes
+// the configuration and its sources were defined at are recorded. Values
+// that could be decoded are kept when a *ValidationError is returned, so
+// the configuration can still be validated.
+func decode(data []byte, file string, format Format, config *FileConfig) error {
+	doc, err := format.parse(data, file)
+	if err != nil {
+		return err
+	}
+
+	version, err := detectVersion(doc)
+	if err != nil {
+		var p problems
+		p.add(origin{file: file, node: doc}, err, "version")
+		return p.err()
+	}
+	err = decoders[version](doc, file, config)
+	config.origin = origin{file: file, node: doc}
+	return err
+}
+
+// detectVersion returns the format version of a configuration document.
+// Files without a version, like partial files that are included, are
+// version 2 when their sources are keyed by name or they set hooks.
+func detectVersion(doc *yaml.Node) (int, error) {
+	root := docRoot(doc)
+	if node := child(root, "version"); node != nil {
+		version, err := strconv.Atoi(node.Value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid version %q", node.Value)
+		}
+		if version == 0 {
+			return Version1, nil
+		}
+		if _, ok := decoders[version]; !ok {
+			return 0, fmt.Errorf("unsupported version %d, expected %s", version, supportedVersions())
+		}
+		return version, nil
+	}
+
+	if sources := child(root, "sources"); (sources != nil && sources.Kind == yaml.MappingNode) || child(root, "hooks") != nil {
+		return Version2, nil
+	}
+	return Version1, nil
+}
+
+// supportedVersions lists the versions with a decoder, e.g. "1 or 2"
+func supportedVersions() string {
+	versions := slices.Sorted(maps.Keys(decoders))
+	names := make([]string, len(versions))
+	for i, v := range versions {
+		names[i] = strconv.Itoa(v)
+	}
+	if len(names) == 1 {
+		return names[0]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
+
+// decodeV1 decodes the version 1 format, which FileConfig mirrors
+func decodeV1(doc *yaml.Node, file string, config *FileConfig) error {
+	err := decodeStrict(doc, file, config)
+	var validationErr *ValidationError
+	if err != nil && !errors.As(err, &validationErr) {
+		return err
+	}
+	if sources := child(docRoot(doc), "sources"); sources != nil {
+		for i := range config.Sources {
+			if node := child(sources, strconv.Itoa(i)); node != nil {
+				config.Sources[i].origin = origin{file: file, node: node}
+			}
+		}
+	}
+	return err
+}
+
+// decodeV2 decodes the version 2 format and converts it to a FileConfig.
+// Sources keep the order they are written in. The hooks set for every
+// source wrap those of the source: their before commands run first and
+// their after commands last.
+func decodeV2(doc *yaml.Node, file string, config *FileConfig) error {
+	var v2 fileConfigV2
+	err := decodeStrict(doc, file, &v2)
+	var validationErr *ValidationError
+	if err != nil && !errors.As(err, &validationErr) {
+		return err
+	}
+
+	*config = FileConfig{
+		Version: v2.Version,
+		Name:    v2.Name,
+		Extends: v2.Extends,
+		Include: v2.Include,
+		Vars:    v2.Vars,
+		Config:  v2.Config,
+		Policy:  v2.Policy,
+	}
+
+	sources := child(docRoot(doc), "sources")
+	if sources == nil || sources.Kind != yaml.MappingNode {
+		return err
+	}
+	for i := 0; i+1 < len(sources.Content); i += 2 {
+		name, node := sources.Content[i].Value, sources.Content[i+1]
+		src := v2.Sources[name]
+		config.Sources = append(config.Sources, Source{
+			Name:         name,
+			URL:          src.URL,
+			Dest:         src.Dest,
+			Timeout:      src.Options.Timeout,
+			Retries:      src.Options.Retries,
+			Backoff:      src.Options.Backoff,
+			GoGetterPath: src.Options.GoGetterPath,
+			Mode:         src.Options.Mode,
+			Weight:       src.Options.Weight,
+			Recursive:    src.Options.Recursive,
+			Checksum:     src.Options.Checksum,
+			Hooks: CommandHooks{
+				Before: slices.Concat(v2.Hooks.Before, src.Hooks.Before),
+				After:  slices.Concat(src.Hooks.After, v2.Hooks.After),
+			},
+			origin: origin{file: file, node: node, options: child(node, "options")},
+		})
+	}
+	return err
+}
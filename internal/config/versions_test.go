@@ -0,0 +1,208 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseV2(t *testing.T) {
+	data := []byte(`version: 2
+name: "v2"
+hooks:
+  before: ["echo before all"]
+  after: ["echo after all"]
+sources:
+  readme:
+    url: "https://example.com/README.md"
+    dest: "README.md"
+    options:
+      timeout: 1m
+      retries: 5
+      mode: file
+    hooks:
+      before: ["echo before readme"]
+      after: ["echo after readme"]
+  license:
+    url: "https://example.com/LICENSE"
+    dest: "LICENSE"
+`)
+
+	cfg, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	if cfg.Version != Version2 || len(cfg.Sources) != 2 {
+		t.Fatalf("Parse() = version %d with %d sources, want version 2 with 2", cfg.Version, len(cfg.Sources))
+	}
+
+	readme, license := cfg.Sources[0], cfg.Sources[1]
+	if readme.Name != "readme" || license.Name != "license" {
+		t.Errorf("source names = %q, %q, want them in file order", readme.Name, license.Name)
+	}
+	if readme.Timeout != time.Minute || readme.Retries == nil || *readme.Retries != 5 || readme.Mode != ModeFile {
+		t.Errorf("readme options = %+v, want timeout, retries and mode set", readme)
+	}
+	wantBefore := []string{"echo before all", "echo before readme"}
+	wantAfter := []string{"echo after readme", "echo after all"}
+	if !slices.Equal(readme.Hooks.Before, wantBefore) || !slices.Equal(readme.Hooks.After, wantAfter) {
+		t.Errorf("readme hooks = %+v, want before %v and after %v", readme.Hooks, wantBefore, wantAfter)
+	}
+	if !slices.Equal(license.Hooks.Before, []string{"echo before all"}) {
+		t.Errorf("license hooks = %+v, want the hooks for every source", license.Hooks)
+	}
+}
+
+func TestParseVersionErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		wantError string
+	}{
+		{
+			name:      "unsupported version",
+			data:      "version: 3\nname: \"future\"\n",
+			wantError: "1:10: unsupported version 3, expected 1 or 2",
+		},
+		{
+			name: "option in the wrong place",
+			data: `version: 2
+name: "v2"
+sources:
+  readme:
+    url: "https://example.com/README.md"
+    dest: "README.md"
+    mode: file
+`,
+			wantError: `7:5: unknown field "mode"`,
+		},
+		{
+			name: "invalid option",
+			data: `version: 2
+name: "v2"
+sources:
+  readme:
+    url: "https://example.com/README.md"
+    dest: "README.md"
+    options:
+      mode: weird
+`,
+			wantError: `8:13: source "readme": invalid mode "weird"`,
+		},
+		{
+			name: "hooks in version 1",
+			data: `version: 1
+name: "v1"
+sources:
+  - url: "https://example.com/README.md"
+    dest: "README.md"
+    hooks:
+      after: ["true"]
+`,
+			wantError: `6:5: unknown field "hooks"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data))
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) || !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("Parse() error = %v, want a *ValidationError containing %q", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	data := []byte(`# Project sources
+version: 1
+name: "project"
+sources:
+  # The readme
+  - url: "https://example.com/README.md"
+    dest: "docs/README.md"
+    # Allow slow mirrors
+    timeout: 1m
+  - url: "https://example.com/other/README.md"
+    dest: "other/README.md" # same base name
+  - url: "https://example.com/${version}/file.txt"
+    dest: "${dest}"
+    checksum: "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+`)
+
+	migrated, err := Migrate(data)
+	if err != nil {
+		t.Fatalf("Migrate() unexpected error: %v", err)
+	}
+	for _, comment := range []string{"# Project sources", "# The readme", "# Allow slow mirrors", "# same base name"} {
+		if !strings.Contains(string(migrated), comment) {
+			t.Errorf("migrated file lost comment %q:\n%s", comment, migrated)
+		}
+	}
+
+	var v1, v2 FileConfig
+	if err := decode(data, "", yamlFormat, &v1); err != nil {
+		t.Fatalf("decode() v1 unexpected error: %v", err)
+	}
+	if err := decode(migrated, "", yamlFormat, &v2); err != nil {
+		t.Fatalf("decode() migrated unexpected error: %v\n%s", err, migrated)
+	}
+	if v2.Version != Version2 || len(v2.Sources) != len(v1.Sources) {
+		t.Fatalf("migrated config = version %d with %d sources, want version 2 with %d", v2.Version, len(v2.Sources), len(v1.Sources))
+	}
+
+	wantNames := []string{"README", "README-2", "source-3"}
+	for i, src := range v2.Sources {
+		if src.Name != wantNames[i] {
+			t.Errorf("Sources[%d].Name = %q, want %q", i, src.Name, wantNames[i])
+		}
+		want := v1.Sources[i]
+		if src.URL != want.URL || src.Dest != want.Dest || src.Timeout != want.Timeout || src.Checksum != want.Checksum {
+			t.Errorf("Sources[%d] = %+v, want the settings of %+v", i, src, want)
+		}
+	}
+
+	if _, err := Migrate(migrated); !errors.Is(err, ErrAlreadyMigrated) {
+		t.Errorf("Migrate() of a version 2 file error = %v, want ErrAlreadyMigrated", err)
+	}
+}
+
+func TestLoadConfigIncludeV1FromV2(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{
+		"tools.yaml": "sources:\n  - url: \"https://example.com/tool\"\n    dest: \"tool\"\n",
+		"main.yaml": `version: 2
+name: "main"
+include: [tools.yaml]
+sources:
+  readme:
+    url: "https://example.com/README.md"
+    dest: "README.md"
+`,
+	})
+
+	cfg, err := LoadConfig(filepath.Join(tmpDir, "main.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfig() unexpected error: %v", err)
+	}
+	if len(cfg.Sources) != 2 || cfg.Sources[0].Dest != "tool" || cfg.Sources[1].Name != "readme" {
+		t.Errorf("Sources = %+v, want the included version 1 source followed by readme", cfg.Sources)
+	}
+}
+
+func TestMigratePartialFile(t *testing.T) {
+	migrated, err := Migrate([]byte("sources:\n  - url: \"https://example.com/a\"\n    dest: \"a\"\n"))
+	if err != nil {
+		t.Fatalf("Migrate() unexpected error: %v", err)
+	}
+	if strings.Contains(string(migrated), "version") {
+		t.Errorf("Migrate() added a version to a partial file:\n%s", migrated)
+	}
+	if !strings.Contains(string(migrated), "  a:\n") {
+		t.Errorf("Migrate() did not key the source by name:\n%s", migrated)
+	}
+}
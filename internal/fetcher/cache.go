@@ -0,0 +1,56 @@
+package fetcher
+
+import (
+	"os"
+	"strings"
+
+	"github.com/universal-development/go-getter-file/internal/cache"
+	"github.com/universal-development/go-getter-file/internal/config"
+)
+
+// cacheEntry returns the cache entry a source is stored under, or nil when
+// caching is disabled or does not apply to the source. Local file sources
+// and sources limited to their top-level files are never cached, and
+// crawled directory listings are keyed apart from a plain fetch of the
+// listing page.
+func (f *Fetcher) cacheEntry(source config.Source) *cache.Entry {
+	if f.cache == nil || (source.Recursive != nil && !*source.Recursive) {
+		return nil
+	}
+
+	resolved, err := f.ResolveURL(source.URL)
+	if err != nil || strings.HasPrefix(resolved, "file::") {
+		return nil
+	}
+	if crawl := f.crawlURL(source); crawl != "" {
+		resolved = "crawl::" + crawl
+	}
+
+	key, normalized, ref := cache.Key(resolved, source.Checksum)
+	if source.Mode != "" && source.Mode != config.ModeAny {
+		// File and dir modes lay out the download differently
+		key += "-" + source.Mode
+	}
+	return &cache.Entry{
+		Key:      key,
+		URL:      normalized,
+		Ref:      ref,
+		Checksum: source.Checksum,
+	}
+}
+
+// restoreFromCache copies a cached download into the staged destination
+func (f *Fetcher) restoreFromCache(key string, staged config.Source) bool {
+	if _, ok := f.cache.Lookup(key); !ok {
+		return false
+	}
+
+	if err := f.cache.Restore(key, staged.Dest); err != nil {
+		f.logger.Warn("failed to restore cached source", "url", staged.URL, "error", err)
+		_ = os.RemoveAll(staged.Dest)
+		return false
+	}
+
+	f.logger.Info("using cached source", "url", staged.URL, "key", key)
+	return true
+}
@@ -0,0 +1,186 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-getter/v2"
+	"github.com/universal-development/go-getter-file/internal/checksum"
+	"github.com/universal-development/go-getter-file/internal/config"
+)
+
+// expectedChecksum returns the checksum configured for a source, with a
+// checksum file already searched for the entry of the downloaded file, or
+// nil when the source has none
+func (f *Fetcher) expectedChecksum(ctx context.Context, source config.Source) (*checksum.Checksum, error) {
+	if source.Checksum == "" {
+		return nil, nil
+	}
+
+	expected, err := checksum.Parse(source.Checksum)
+	if err != nil {
+		return nil, err
+	}
+	if expected.URL != "" {
+		if err := f.checkPolicies(expected.URL); err != nil {
+			return nil, fmt.Errorf("checksum file: %w", err)
+		}
+		return f.resolveChecksum(ctx, expected.URL, urlBaseName(source.URL))
+	}
+	return expected, nil
+}
+
+// verifyChecksum checks the downloaded artifact of the staged source
+// against the expected checksum. Mismatches are reported for the final
+// destination of the source rather than its staging path.
+func verifyChecksum(expected *checksum.Checksum, staged config.Source, dest string) error {
+	artifact := ArtifactPath(staged)
+	err := expected.Verify(artifact)
+
+	var mismatch *checksum.MismatchError
+	if errors.As(err, &mismatch) {
+		if rel, relErr := filepath.Rel(staged.Dest, artifact); relErr == nil {
+			mismatch.Path = filepath.Join(dest, rel)
+		}
+	}
+	return err
+}
+
+// withChecksum adds the expected checksum to a source URL for go-getter,
+// which verifies an archive before it is extracted
+func withChecksum(src string, expected *checksum.Checksum) string {
+	sep := "?"
+	if strings.Contains(src, "?") {
+		sep = "&"
+	}
+	return src + sep + "checksum=" + url.QueryEscape(expected.String())
+}
+
+// archiveMismatch converts a checksum error of go-getter into a
+// *checksum.MismatchError for dest
+func archiveMismatch(err error, expected *checksum.Checksum, dest string) error {
+	var sumErr *getter.ChecksumError
+	if !errors.As(err, &sumErr) {
+		return err
+	}
+	return &checksum.MismatchError{
+		Path:     dest,
+		Expected: expected.String(),
+		Actual:   expected.Algorithm + ":" + hex.EncodeToString(sumErr.Actual),
+	}
+}
+
+// isArchive reports whether go-getter extracts the download of src, either
+// because of its archive query parameter or the extension of its path
+func (f *Fetcher) isArchive(src string) bool {
+	if _, rest, ok := strings.Cut(src, "::"); ok {
+		src = rest
+	}
+	src, _ = getter.SourceDirSubdir(src)
+
+	u, err := url.Parse(src)
+	if err != nil {
+		return false
+	}
+
+	decompressors := f.decompressors
+	if decompressors == nil {
+		decompressors = getter.Decompressors
+	}
+
+	if archive := u.Query().Get("archive"); archive != "" {
+		if b, err := strconv.ParseBool(archive); err == nil && !b {
+			return false
+		}
+		return decompressors[archive] != nil
+	}
+	for ext := range decompressors {
+		if strings.HasSuffix(u.Path, "."+ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveChecksum downloads a checksum file and extracts the entry for filename
+func (f *Fetcher) resolveChecksum(ctx context.Context, checksumURL, filename string) (*checksum.Checksum, error) {
+	tmpDir, err := os.MkdirTemp("", "go-getter-file-checksum")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	dst := filepath.Join(tmpDir, "checksum")
+	client := f.client()
+	req := &getter.Request{
+		Src:     checksumURL,
+		Dst:     dst,
+		Pwd:     f.pwd,
+		GetMode: getter.ModeFile,
+	}
+
+	if _, err := client.Get(ctx, req); err != nil {
+		return nil, fmt.Errorf("failed to download checksum file %s: %w", checksumURL, err)
+	}
+
+	file, err := os.Open(dst)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	sum, err := checksum.FromFile(file, filename)
+	if err != nil {
+		return nil, fmt.Errorf("checksum file %s: %w", checksumURL, err)
+	}
+
+	return sum, nil
+}
+
+// ArtifactPath returns the path of the downloaded artifact. go-getter saves
+// single files inside the destination directory using the base name of the
+// URL, so that file is preferred over hashing the whole directory.
+func ArtifactPath(source config.Source) string {
+	info, err := os.Stat(source.Dest)
+	if err != nil || !info.IsDir() {
+		return source.Dest
+	}
+
+	if name := urlBaseName(source.URL); name != "" {
+		candidate := filepath.Join(source.Dest, name)
+		if info, err := os.Stat(candidate); err == nil && info.Mode().IsRegular() {
+			return candidate
+		}
+	}
+
+	return source.Dest
+}
+
+// urlBaseName returns the file name go-getter would use for a URL
+func urlBaseName(rawURL string) string {
+	if _, rest, ok := strings.Cut(rawURL, "::"); ok {
+		rawURL = rest
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	if name := u.Query().Get("filename"); name != "" {
+		return name
+	}
+
+	name := path.Base(u.Path)
+	if name == "." || name == "/" {
+		return ""
+	}
+	return name
+}
@@ -0,0 +1,47 @@
+package fetcher
+
+import (
+	"context"
+
+	"github.com/universal-development/go-getter-file/internal/config"
+)
+
+// Downloader downloads a source to its destination. The destination is a
+// staging path that the fetcher verifies, caches and commits afterwards.
+type Downloader interface {
+	Download(ctx context.Context, source config.Source) error
+}
+
+// DownloaderFunc adapts a function to the Downloader interface
+type DownloaderFunc func(ctx context.Context, source config.Source) error
+
+// Download calls fn
+func (fn DownloaderFunc) Download(ctx context.Context, source config.Source) error {
+	return fn(ctx, source)
+}
+
+// WithDownloader downloads every source with d instead of go-getter, so
+// tests can substitute a fake for network access
+func WithDownloader(d Downloader) Option {
+	return func(f *Fetcher) {
+		f.downloader = d
+	}
+}
+
+// downloaderFor returns the downloader used for a source: the configured
+// downloader, the directory listing crawler, an external go-getter binary
+// or the embedded library
+func (f *Fetcher) downloaderFor(source config.Source) Downloader {
+	if f.downloader != nil {
+		return f.downloader
+	}
+	if crawl := f.crawlURL(source); crawl != "" {
+		return DownloaderFunc(func(ctx context.Context, staged config.Source) error {
+			return crawlHTTP(ctx, crawl, staged.Dest, f.progress)
+		})
+	}
+	if f.goGetterPath(source) != "" {
+		return DownloaderFunc(f.fetchExternal)
+	}
+	return DownloaderFunc(f.fetchEmbedded)
+}
@@ -2,11 +2,15 @@ package fetcher
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os/exec"
+	"path/filepath"
 	"time"
 
 	"github.com/hashicorp/go-getter/v2"
+	"github.com/universal-development/go-getter-file/internal/cache"
 	"github.com/universal-development/go-getter-file/internal/config"
 )
 
@@ -14,65 +18,281 @@ import (
 type Fetcher struct {
 	config         config.Config
 	useExternalBin bool
+	logger         *slog.Logger
+	progress       getter.ProgressTracker
+	cache          *cache.Cache
+	getters        []namedGetter
+	detectors      []getter.Detector
+	decompressors  map[string]getter.Decompressor
+	downloader     Downloader
+	policies       []*config.Policy
+	verify         VerifyFunc
+	pwd            string
+}
+
+// ErrVerifyFailed is returned when the verify function of the fetcher
+// rejects a download
+var ErrVerifyFailed = errors.New("verification failed")
+
+// VerifyFunc checks a download before it replaces the destination of
+// source. stagedDest is the path the download was staged at.
+type VerifyFunc func(ctx context.Context, source config.Source, stagedDest string) error
+
+// Option configures a Fetcher
+type Option func(*Fetcher)
+
+// WithLogger sets the logger retries and cache events are logged to
+func WithLogger(l *slog.Logger) Option {
+	return func(f *Fetcher) {
+		f.logger = l
+	}
+}
+
+// WithProgress reports the progress of downloads made with the embedded
+// library and of crawled directory listings to tracker
+func WithProgress(tracker getter.ProgressTracker) Option {
+	return func(f *Fetcher) {
+		f.progress = tracker
+	}
+}
+
+// WithPolicy checks every source against p before it is downloaded. It may
+// be given several times, a source has to satisfy all policies.
+func WithPolicy(p *config.Policy) Option {
+	return func(f *Fetcher) {
+		if p != nil {
+			f.policies = append(f.policies, p)
+		}
+	}
+}
+
+// WithVerify checks every download with verify before it is moved into its
+// destination. A rejected download leaves the destination untouched and is
+// not retried.
+func WithVerify(verify VerifyFunc) Option {
+	return func(f *Fetcher) {
+		f.verify = verify
+	}
+}
+
+// WithPwd resolves relative local sources against dir instead of the
+// working directory
+func WithPwd(dir string) Option {
+	return func(f *Fetcher) {
+		f.pwd = dir
+	}
 }
 
 // New creates a new Fetcher instance
-func New(cfg config.Config) *Fetcher {
-	return &Fetcher{
+func New(cfg config.Config, opts ...Option) *Fetcher {
+	f := &Fetcher{
 		config:         cfg,
 		useExternalBin: cfg.GoGetterPath != "",
+		logger:         slog.Default(),
+	}
+	if cfg.CacheDir != "" {
+		f.cache = cache.New(cfg.CacheDir)
+	}
+	for _, opt := range opts {
+		opt(f)
 	}
+	// go-getter resolves relative local sources and links them from the
+	// destination, so the directory has to be absolute
+	if pwd, err := filepath.Abs(f.pwd); err == nil {
+		f.pwd = pwd
+	}
+	for _, name := range f.unknownGetters() {
+		f.logger.Warn("unknown getter in allowlist", "getter", name)
+	}
+	return f
+}
+
+// Result describes how fetching a single source went
+type Result struct {
+	// Attempts is the number of downloads that were started
+	Attempts int
+	// Duration is the time spent on all attempts including retry waits
+	Duration time.Duration
+	// Bytes is the size of the fetched content, excluding VCS metadata
+	Bytes int64
+	// Cached reports whether the content was restored from the cache
+	Cached bool
 }
 
 // FetchSource downloads a single source with retries
 func (f *Fetcher) FetchSource(ctx context.Context, source config.Source) error {
+	_, err := f.Fetch(ctx, source)
+	return err
+}
+
+// Fetch downloads a single source with retries and reports the attempts,
+// duration and size of the download. The result is filled in on failure too.
+func (f *Fetcher) Fetch(ctx context.Context, source config.Source) (Result, error) {
 	timeout := source.Timeout
 	if timeout == 0 {
 		timeout = f.config.Timeout
 	}
 
 	retries := f.config.Retries
+	if source.Retries != nil {
+		retries = *source.Retries
+	}
+	backoff := f.config.Backoff.Merge(source.Backoff)
+	var result Result
 	var lastErr error
+	start := time.Now()
 
 	for attempt := 0; attempt <= retries; attempt++ {
 		if attempt > 0 {
-			fmt.Printf("  Retry %d/%d for %s\n", attempt, retries, source.URL)
+			delay := backoffDelay(backoff, attempt)
+			f.logger.Warn("retrying source", "url", source.URL, "attempt", attempt, "retries", retries,
+				"delay", delay.Round(time.Millisecond), "error", lastErr)
+			if err := wait(ctx, delay); err != nil {
+				result.Duration = time.Since(start)
+				return result, fmt.Errorf("cancelled while waiting to retry: %w", lastErr)
+			}
 		}
 
-		err := f.fetch(ctx, source, timeout)
+		result.Attempts++
+		err := f.fetch(ctx, source, timeout, &result)
 		if err == nil {
-			return nil
+			result.Duration = time.Since(start)
+			return result, nil
 		}
 
 		lastErr = err
-		if attempt < retries {
-			// Wait a bit before retrying
-			time.Sleep(time.Second * time.Duration(attempt+1))
+		if ctx.Err() != nil {
+			break
+		}
+		if !retryable(err) {
+			result.Duration = time.Since(start)
+			return result, fmt.Errorf("permanent error, not retrying: %w", err)
 		}
 	}
 
-	return fmt.Errorf("failed after %d retries: %w", retries, lastErr)
+	result.Duration = time.Since(start)
+	return result, fmt.Errorf("failed after %d retries: %w", result.Attempts-1, lastErr)
 }
 
-// fetch performs the actual download
-func (f *Fetcher) fetch(ctx context.Context, source config.Source, timeout time.Duration) error {
+// fetch performs the actual download and records its size in result
+func (f *Fetcher) fetch(ctx context.Context, source config.Source, timeout time.Duration, result *Result) error {
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	if f.useExternalBin {
-		return f.fetchExternal(ctx, source)
+	if err := f.config.ConfineDest(source.Dest); err != nil {
+		return err
+	}
+	if err := config.CheckDest(source.Dest, ""); err != nil {
+		return err
+	}
+
+	stage, err := newStaging(source.Dest)
+	if err != nil {
+		return err
+	}
+	defer stage.cleanup()
+
+	staged := source
+	staged.Dest = stage.path
+	if staged.URL, err = f.detect(source.URL); err != nil {
+		return err
+	}
+	if err := f.checkGetter(staged.URL); err != nil {
+		return err
+	}
+	if err := f.checkPolicies(staged.URL); err != nil {
+		return err
+	}
+
+	expected, err := f.expectedChecksum(ctx, source)
+	if err != nil {
+		return err
 	}
+	// go-getter verifies archives before extracting them, the extracted
+	// content could never match the checksum of the archive
+	archive := expected != nil && f.isArchive(staged.URL)
 
-	return f.fetchEmbedded(ctx, source)
+	entry := f.cacheEntry(source)
+	cached := entry != nil && f.restoreFromCache(entry.Key, staged)
+
+	if !cached {
+		download := staged
+		if archive {
+			download.URL = withChecksum(staged.URL, expected)
+		}
+		if err := f.downloaderFor(source).Download(ctx, download); err != nil {
+			if archive {
+				return archiveMismatch(err, expected, source.Dest)
+			}
+			return err
+		}
+	}
+
+	if source.Recursive != nil {
+		if err := applyRecursive(staged.Dest, *source.Recursive); err != nil {
+			return fmt.Errorf("failed to apply recursive setting to %s: %w", source.URL, err)
+		}
+	}
+
+	if f.config.OutputRoot != "" {
+		if err := sandbox(staged.Dest); err != nil {
+			return err
+		}
+	}
+
+	if expected != nil && !archive {
+		if err := verifyChecksum(expected, staged, source.Dest); err != nil {
+			if cached {
+				// Drop the bad entry so the next attempt downloads again
+				_ = f.cache.Remove(entry.Key)
+			}
+			return err
+		}
+	}
+
+	if f.verify != nil {
+		if err := f.verify(ctx, source, staged.Dest); err != nil {
+			return fmt.Errorf("%w: %w", ErrVerifyFailed, err)
+		}
+	}
+
+	if entry != nil && !cached {
+		if err := f.cache.Store(*entry, staged.Dest); err != nil {
+			f.logger.Warn("failed to cache source", "url", source.URL, "error", err)
+		}
+	}
+
+	size, err := diskSize(staged.Dest)
+	if err != nil {
+		return err
+	}
+
+	// Do not replace the destination once the fetch was cancelled
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := stage.commit(); err != nil {
+		return err
+	}
+
+	result.Bytes = size
+	result.Cached = cached
+	return nil
 }
 
 // fetchEmbedded uses the embedded go-getter library
 func (f *Fetcher) fetchEmbedded(ctx context.Context, source config.Source) error {
-	client := &getter.Client{}
+	client := f.client()
 
 	req := &getter.Request{
-		Src: source.URL,
-		Dst: source.Dest,
+		Src:     source.URL,
+		Dst:     source.Dest,
+		Pwd:     f.pwd,
+		GetMode: getterMode(source.Mode),
+	}
+	if f.progress != nil {
+		req.ProgressListener = f.progress
 	}
 
 	_, err := client.Get(ctx, req)
@@ -85,9 +305,20 @@ func (f *Fetcher) fetchEmbedded(ctx context.Context, source config.Source) error
 
 // fetchExternal uses an external go-getter binary
 func (f *Fetcher) fetchExternal(ctx context.Context, source config.Source) error {
-	args := []string{source.URL, source.Dest}
+	var args []string
+	if source.Mode != "" {
+		args = append(args, "-mode="+source.Mode)
+	}
+	dest, err := filepath.Abs(source.Dest)
+	if err != nil {
+		return err
+	}
+	args = append(args, source.URL, dest)
 
-	cmd := exec.CommandContext(ctx, f.config.GoGetterPath, args...)
+	// The binary resolves relative local sources against its working
+	// directory
+	cmd := exec.CommandContext(ctx, f.goGetterPath(source), args...)
+	cmd.Dir = f.pwd
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("external go-getter failed for %s: %w\nOutput: %s",
@@ -96,3 +327,27 @@ func (f *Fetcher) fetchExternal(ctx context.Context, source config.Source) error
 
 	return nil
 }
+
+// goGetterPath returns the external go-getter binary used for a source, or
+// an empty string when the embedded library is used. A source may set an
+// empty go-getter-path to use the library despite a global binary.
+func (f *Fetcher) goGetterPath(source config.Source) string {
+	if source.GoGetterPath != nil {
+		return *source.GoGetterPath
+	}
+	if f.useExternalBin {
+		return f.config.GoGetterPath
+	}
+	return ""
+}
+
+// getterMode maps a source mode to the go-getter request mode
+func getterMode(mode string) getter.Mode {
+	switch mode {
+	case config.ModeFile:
+		return getter.ModeFile
+	case config.ModeDir:
+		return getter.ModeDir
+	}
+	return getter.ModeAny
+}
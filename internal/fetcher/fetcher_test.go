@@ -1,9 +1,24 @@
 package fetcher
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/universal-development/go-getter-file/internal/checksum"
 	"github.com/universal-development/go-getter-file/internal/config"
 )
 
@@ -140,3 +155,264 @@ func TestFetchSourceTimeout(t *testing.T) {
 	// The actual fetch would fail without network, but we've verified the structure
 	// Integration tests would cover the actual fetching behavior
 }
+
+func TestFetchSourceChecksum(t *testing.T) {
+	archive := tarGz(t, map[string]string{"hello.txt": "hello"})
+	archiveSum := fmt.Sprintf("%x", sha256.Sum256(archive))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/hello.txt":
+			fmt.Fprint(w, "hello")
+		case "/hello.tar.gz":
+			w.Write(archive)
+		case "/SHA256SUMS":
+			fmt.Fprintln(w, archiveSum+"  hello.tar.gz")
+			fmt.Fprintln(w, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824  hello.txt")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		file      string
+		checksum  string
+		wantError bool
+	}{
+		{
+			name:     "matching checksum",
+			file:     "hello.txt",
+			checksum: "sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+		},
+		{
+			name:     "checksum file",
+			file:     "hello.txt",
+			checksum: server.URL + "/SHA256SUMS",
+		},
+		{
+			name:      "mismatching checksum",
+			file:      "hello.txt",
+			checksum:  "md5:00000000000000000000000000000000",
+			wantError: true,
+		},
+		{
+			name:     "archive checksum",
+			file:     "hello.tar.gz",
+			checksum: "sha256:" + archiveSum,
+		},
+		{
+			name:     "archive checksum file",
+			file:     "hello.tar.gz",
+			checksum: "file:" + server.URL + "/SHA256SUMS",
+		},
+		{
+			name:      "mismatching archive checksum",
+			file:      "hello.tar.gz",
+			checksum:  "md5:00000000000000000000000000000000",
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "out")
+			f := New(config.Config{Timeout: 10 * time.Second})
+
+			err := f.FetchSource(context.Background(), config.Source{
+				URL:      server.URL + "/" + tt.file,
+				Dest:     dest,
+				Checksum: tt.checksum,
+			})
+
+			// The archive is extracted into dest, so both leave hello.txt
+			artifact := filepath.Join(dest, "hello.txt")
+			_, statErr := os.Stat(artifact)
+			if tt.wantError {
+				var mismatch *checksum.MismatchError
+				if !errors.As(err, &mismatch) {
+					t.Fatalf("FetchSource() error = %v, want a checksum mismatch", err)
+				}
+				if !strings.HasPrefix(mismatch.Path, dest) || strings.Contains(mismatch.Path, ".staging-") {
+					t.Errorf("mismatch path = %s, want it in %s", mismatch.Path, dest)
+				}
+				if !os.IsNotExist(statErr) {
+					t.Errorf("Expected artifact %s to be removed after mismatch", artifact)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FetchSource() unexpected error: %v", err)
+			}
+			if statErr != nil {
+				t.Errorf("Expected artifact %s to exist: %v", artifact, statErr)
+			}
+		})
+	}
+}
+
+// tarGz returns a gzipped tarball of files
+func tarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestFetchSourceCache(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			requests++
+		}
+		fmt.Fprint(w, "hello")
+	}))
+	defer server.Close()
+
+	cfg := config.Config{
+		Timeout:  10 * time.Second,
+		CacheDir: filepath.Join(t.TempDir(), "cache"),
+	}
+
+	for i := 0; i < 2; i++ {
+		dest := filepath.Join(t.TempDir(), "out")
+		result, err := New(cfg, WithLogger(slog.New(slog.DiscardHandler))).Fetch(context.Background(), config.Source{
+			URL:  server.URL + "/hello.txt",
+			Dest: dest,
+		})
+		if err != nil {
+			t.Fatalf("Fetch() attempt %d unexpected error: %v", i, err)
+		}
+		if result.Attempts != 1 || result.Bytes != 5 || result.Cached != (i == 1) {
+			t.Errorf("attempt %d: result = %+v, want 1 attempt, 5 bytes, cached %v", i, result, i == 1)
+		}
+
+		data, err := os.ReadFile(filepath.Join(dest, "hello.txt"))
+		if err != nil || string(data) != "hello" {
+			t.Errorf("attempt %d: content = %q (%v), want hello", i, string(data), err)
+		}
+	}
+
+	if requests != 1 {
+		t.Errorf("server received %d downloads, want 1 (second fetch should use the cache)", requests)
+	}
+}
+
+func TestFetchSourceOverrides(t *testing.T) {
+	var failing bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "hello")
+	}))
+	defer server.Close()
+
+	// The global config points at a binary that does not exist, so only
+	// sources that opt back into the embedded library can succeed
+	cfg := config.Config{
+		Retries:      3,
+		Timeout:      10 * time.Second,
+		GoGetterPath: filepath.Join(t.TempDir(), "missing-go-getter"),
+	}
+	embedded := ""
+	noRetries := 0
+
+	t.Run("file mode with embedded getter", func(t *testing.T) {
+		dest := filepath.Join(t.TempDir(), "hello.txt")
+		result, err := New(cfg, WithLogger(slog.New(slog.DiscardHandler))).Fetch(context.Background(), config.Source{
+			URL:          server.URL + "/download",
+			Dest:         dest,
+			Mode:         config.ModeFile,
+			GoGetterPath: &embedded,
+		})
+		if err != nil {
+			t.Fatalf("Fetch() unexpected error: %v", err)
+		}
+		if result.Attempts != 1 {
+			t.Errorf("Fetch() attempts = %d, want 1", result.Attempts)
+		}
+		data, err := os.ReadFile(dest)
+		if err != nil || string(data) != "hello" {
+			t.Errorf("dest content = %q (%v), want hello written to the file itself", string(data), err)
+		}
+	})
+
+	t.Run("global external binary", func(t *testing.T) {
+		_, err := New(cfg, WithLogger(slog.New(slog.DiscardHandler))).Fetch(context.Background(), config.Source{
+			URL:     server.URL + "/hello.txt",
+			Dest:    filepath.Join(t.TempDir(), "out"),
+			Retries: &noRetries,
+		})
+		if err == nil {
+			t.Fatal("Fetch() expected error from missing external binary, got nil")
+		}
+	})
+
+	t.Run("source retries", func(t *testing.T) {
+		failing = true
+		defer func() { failing = false }()
+
+		result, err := New(cfg, WithLogger(slog.New(slog.DiscardHandler))).Fetch(context.Background(), config.Source{
+			URL:          server.URL + "/hello.txt",
+			Dest:         filepath.Join(t.TempDir(), "out"),
+			Retries:      &noRetries,
+			GoGetterPath: &embedded,
+		})
+		if err == nil {
+			t.Fatal("Fetch() expected error, got nil")
+		}
+		if result.Attempts != 1 {
+			t.Errorf("Fetch() attempts = %d, want 1 with retries: 0", result.Attempts)
+		}
+	})
+}
+
+// countingTracker records the sources whose progress was tracked
+type countingTracker struct {
+	sources []string
+}
+
+func (c *countingTracker) TrackProgress(src string, currentSize, totalSize int64, stream io.ReadCloser) io.ReadCloser {
+	c.sources = append(c.sources, src)
+	return stream
+}
+
+func TestFetchSourceProgress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "hello")
+	}))
+	defer server.Close()
+
+	tracker := &countingTracker{}
+	f := New(config.Config{Timeout: 10 * time.Second}, WithLogger(slog.New(slog.DiscardHandler)), WithProgress(tracker))
+	err := f.FetchSource(context.Background(), config.Source{
+		URL:  server.URL + "/hello.txt",
+		Dest: filepath.Join(t.TempDir(), "out"),
+	})
+	if err != nil {
+		t.Fatalf("FetchSource() unexpected error: %v", err)
+	}
+
+	if len(tracker.sources) != 1 {
+		t.Errorf("tracked %d downloads, want 1", len(tracker.sources))
+	}
+}
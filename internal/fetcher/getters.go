@@ -0,0 +1,151 @@
+package fetcher
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/go-getter/v2"
+)
+
+// ErrGetterNotAllowed is returned for sources that resolve to a getter
+// missing from the getters allowlist of the configuration
+var ErrGetterNotAllowed = errors.New("getter not allowed")
+
+// namedGetter is a go-getter implementation with the name it is forced with
+// in URLs and listed under in the getters allowlist
+type namedGetter struct {
+	name   string
+	getter getter.Getter
+}
+
+// WithGetter registers a getter under name, e.g. s3 for s3:: URLs. Custom
+// getters are asked before the built-in ones and replace the built-in
+// getter of the same name.
+func WithGetter(name string, g getter.Getter) Option {
+	return func(f *Fetcher) {
+		f.getters = slices.DeleteFunc(f.getters, func(ng namedGetter) bool {
+			return ng.name == name
+		})
+		f.getters = append(f.getters, namedGetter{name: name, getter: g})
+	}
+}
+
+// WithDetector registers a detector that rewrites source URLs before a
+// getter is chosen, e.g. to expand a shorthand into a full URL. Detectors
+// are asked in the order they were registered and the first match wins.
+func WithDetector(d getter.Detector) Option {
+	return func(f *Fetcher) {
+		f.detectors = append(f.detectors, d)
+	}
+}
+
+// WithDecompressor registers a decompressor for archives with the given
+// extension, replacing the built-in one for that extension
+func WithDecompressor(ext string, d getter.Decompressor) Option {
+	return func(f *Fetcher) {
+		if f.decompressors == nil {
+			f.decompressors = maps.Clone(getter.Decompressors)
+		}
+		f.decompressors[ext] = d
+	}
+}
+
+// allGetters returns the custom getters followed by the built-in getters
+// they do not replace
+func (f *Fetcher) allGetters() []namedGetter {
+	all := slices.Clone(f.getters)
+	for _, g := range getter.Getters {
+		name := getterName(g)
+		if !slices.ContainsFunc(f.getters, func(ng namedGetter) bool { return ng.name == name }) {
+			all = append(all, namedGetter{name: name, getter: g})
+		}
+	}
+	return all
+}
+
+// allowed reports whether the getters allowlist permits the named getter
+func (f *Fetcher) allowed(name string) bool {
+	return len(f.config.Getters) == 0 || slices.Contains(f.config.Getters, name)
+}
+
+// client returns a go-getter client limited to the allowed getters
+func (f *Fetcher) client() *getter.Client {
+	var getters []getter.Getter
+	for _, ng := range f.allGetters() {
+		if f.allowed(ng.name) {
+			getters = append(getters, ng.getter)
+		}
+	}
+	return &getter.Client{
+		Getters:       getters,
+		Decompressors: f.decompressors,
+	}
+}
+
+// detect rewrites src with the first registered detector that matches it
+func (f *Fetcher) detect(src string) (string, error) {
+	for _, d := range f.detectors {
+		result, ok, err := d.Detect(src, f.pwd)
+		if err != nil {
+			return "", fmt.Errorf("failed to detect %s: %w", src, err)
+		}
+		if ok {
+			return result, nil
+		}
+	}
+	return src, nil
+}
+
+// checkGetter returns ErrGetterNotAllowed when src resolves to a getter
+// the allowlist does not contain
+func (f *Fetcher) checkGetter(src string) error {
+	if len(f.config.Getters) == 0 {
+		return nil
+	}
+
+	resolved, err := f.ResolveURL(src)
+	if err != nil {
+		return err
+	}
+	name, _, ok := strings.Cut(resolved, "::")
+	if !ok || !f.allowed(name) {
+		return fmt.Errorf("%w: %s resolves to getter %q, allowed getters are %s",
+			ErrGetterNotAllowed, src, name, strings.Join(f.config.Getters, ", "))
+	}
+	return nil
+}
+
+// checkPolicies checks src against the policies once the detectors have
+// turned it into a full URL, e.g. github.com/org/repo into
+// git::https://github.com/org/repo.git
+func (f *Fetcher) checkPolicies(src string) error {
+	if len(f.policies) == 0 {
+		return nil
+	}
+
+	resolved, err := f.ResolveURL(src)
+	if err != nil {
+		return err
+	}
+	for _, p := range f.policies {
+		if err := p.Check(resolved); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unknownGetters returns the names in the allowlist no getter is registered under
+func (f *Fetcher) unknownGetters() []string {
+	var unknown []string
+	all := f.allGetters()
+	for _, name := range f.config.Getters {
+		if !slices.ContainsFunc(all, func(ng namedGetter) bool { return ng.name == name }) {
+			unknown = append(unknown, name)
+		}
+	}
+	return unknown
+}
@@ -0,0 +1,288 @@
+package fetcher
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-getter/v2"
+	"github.com/universal-development/go-getter-file/internal/config"
+)
+
+// memGetter serves mem:// URLs from a map of file contents
+type memGetter struct {
+	files map[string]string
+}
+
+func (g *memGetter) Get(ctx context.Context, req *getter.Request) error {
+	return errors.New("mem getter does not support directories")
+}
+
+func (g *memGetter) GetFile(ctx context.Context, req *getter.Request) error {
+	u, err := url.Parse(req.Src)
+	if err != nil {
+		return err
+	}
+	content, ok := g.files[u.Host]
+	if !ok {
+		return fmt.Errorf("mem file %s not found", u.Host)
+	}
+	return os.WriteFile(req.Dst, []byte(content), 0644)
+}
+
+func (g *memGetter) Mode(ctx context.Context, u *url.URL) (getter.Mode, error) {
+	return getter.ModeFile, nil
+}
+
+func (g *memGetter) Detect(req *getter.Request) (bool, error) {
+	return req.Forced == "mem" || strings.HasPrefix(req.Src, "mem://"), nil
+}
+
+// docsDetector expands docs:<name> into a mem:// URL
+type docsDetector struct{}
+
+func (docsDetector) Detect(src, pwd string) (string, bool, error) {
+	name, ok := strings.CutPrefix(src, "docs:")
+	if !ok {
+		return "", false, nil
+	}
+	return "mem://" + name, true, nil
+}
+
+func testConfig() config.Config {
+	return config.Config{Parallelism: 1, Timeout: 5 * time.Second}
+}
+
+func TestCustomGetterAndDetector(t *testing.T) {
+	g := &memGetter{files: map[string]string{"readme": "hello"}}
+	f := New(testConfig(),
+		WithLogger(slog.New(slog.DiscardHandler)),
+		WithGetter("mem", g),
+		WithDetector(docsDetector{}))
+
+	resolved, err := f.ResolveURL("docs:readme")
+	if err != nil {
+		t.Fatalf("ResolveURL() unexpected error: %v", err)
+	}
+	if resolved != "mem::mem://readme" {
+		t.Errorf("ResolveURL() = %q, want mem::mem://readme", resolved)
+	}
+
+	dest := filepath.Join(t.TempDir(), "readme.txt")
+	if err := f.FetchSource(context.Background(), config.Source{URL: "docs:readme", Dest: dest, Mode: config.ModeFile}); err != nil {
+		t.Fatalf("FetchSource() unexpected error: %v", err)
+	}
+	content, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatalf("Failed to read downloaded file: %v", err)
+	}
+	if string(content) != "hello" {
+		t.Errorf("content = %q, want %q", content, "hello")
+	}
+}
+
+func TestGettersAllowlist(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "hello")
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		url     string
+		getters []string
+		wantErr bool
+	}{
+		{name: "all getters", getters: nil},
+		{name: "http allowed", getters: []string{"git", "http"}},
+		{name: "http not allowed", getters: []string{"git"}, wantErr: true},
+		{name: "relative path not allowed", url: "./vendor/file.txt", getters: []string{"http"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.Retries = 2
+			cfg.Getters = tt.getters
+			f := New(cfg, WithLogger(slog.New(slog.DiscardHandler)))
+
+			dest := filepath.Join(t.TempDir(), "file.txt")
+			url := cmp.Or(tt.url, server.URL+"/file.txt")
+			result, err := f.Fetch(context.Background(), config.Source{URL: url, Dest: dest, Mode: config.ModeFile})
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("Fetch() unexpected error: %v", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, ErrGetterNotAllowed) {
+				t.Fatalf("Fetch() error = %v, want ErrGetterNotAllowed", err)
+			}
+			if result.Attempts != 1 {
+				t.Errorf("Attempts = %d, want 1 for a disallowed getter", result.Attempts)
+			}
+			if _, err := os.Stat(dest); !os.IsNotExist(err) {
+				t.Errorf("dest exists after a disallowed fetch: %v", err)
+			}
+		})
+	}
+}
+
+func TestWithDownloader(t *testing.T) {
+	var got config.Source
+	fake := DownloaderFunc(func(ctx context.Context, source config.Source) error {
+		got = source
+		return os.WriteFile(source.Dest, []byte("fake"), 0644)
+	})
+	f := New(testConfig(), WithLogger(slog.New(slog.DiscardHandler)), WithDownloader(fake))
+
+	dest := filepath.Join(t.TempDir(), "README.md")
+	source := config.Source{URL: "github.com/org/repo//README.md", Dest: dest}
+	result, err := f.Fetch(context.Background(), source)
+	if err != nil {
+		t.Fatalf("Fetch() unexpected error: %v", err)
+	}
+
+	if got.URL != source.URL {
+		t.Errorf("downloader URL = %q, want %q", got.URL, source.URL)
+	}
+	if got.Dest == dest {
+		t.Error("downloader got the final destination, want a staging path")
+	}
+	if result.Bytes != 4 {
+		t.Errorf("Bytes = %d, want 4", result.Bytes)
+	}
+
+	file, err := os.Open(dest)
+	if err != nil {
+		t.Fatalf("Failed to open committed file: %v", err)
+	}
+	defer file.Close()
+	if content, _ := io.ReadAll(file); string(content) != "fake" {
+		t.Errorf("content = %q, want %q", content, "fake")
+	}
+}
+
+func TestRelativeLocalSource(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, "vendor", "tools", "tool.txt"), "tool")
+
+	cfg := testConfig()
+	cfg.OutputRoot = tmpDir
+	cfg.Getters = []string{"file"}
+	f := New(cfg, WithLogger(slog.New(slog.DiscardHandler)), WithPwd(tmpDir))
+
+	resolved, err := f.ResolveURL("./vendor/tools")
+	if err != nil {
+		t.Fatalf("ResolveURL() unexpected error: %v", err)
+	}
+	if want := "file::" + filepath.Join(tmpDir, "vendor", "tools"); resolved != want {
+		t.Errorf("ResolveURL() = %q, want %q", resolved, want)
+	}
+
+	source := config.Source{URL: "./vendor/tools", Dest: filepath.Join(tmpDir, "out")}
+	if err := f.FetchSource(context.Background(), source); err != nil {
+		t.Fatalf("FetchSource() unexpected error: %v", err)
+	}
+	artifact, err := f.Inspect(context.Background(), source)
+	if err != nil {
+		t.Fatalf("Inspect() unexpected error: %v", err)
+	}
+	if artifact.ResolvedURL != resolved || artifact.Hash == "" {
+		t.Errorf("Inspect() = %+v, want resolved url %s and a hash", artifact, resolved)
+	}
+}
+
+func TestPolicyAfterDetection(t *testing.T) {
+	var downloads int
+	fake := DownloaderFunc(func(ctx context.Context, source config.Source) error {
+		downloads++
+		return os.WriteFile(source.Dest, []byte("fake"), 0644)
+	})
+
+	tests := []struct {
+		name     string
+		url      string
+		checksum string
+		policy   *config.Policy
+		wantErr  bool
+	}{
+		{
+			name:   "shorthand resolved to an allowed host",
+			url:    "github.com/acme/tools",
+			policy: &config.Policy{AllowedSchemes: []string{"https"}, AllowedHosts: []string{"github.com"}},
+		},
+		{
+			name:    "shorthand resolved to a denied scheme",
+			url:     "github.com/acme/tools",
+			policy:  &config.Policy{AllowedSchemes: []string{"ssh"}},
+			wantErr: true,
+		},
+		{
+			name:    "detector rewrite checked",
+			url:     "docs:readme",
+			policy:  &config.Policy{AllowedSchemes: []string{"https"}},
+			wantErr: true,
+		},
+		{
+			name:    "relative path checked as file",
+			url:     "./vendor/tools",
+			policy:  &config.Policy{AllowedSchemes: []string{"https"}},
+			wantErr: true,
+		},
+		{
+			name:   "relative path allowed as file",
+			url:    "./vendor/tools",
+			policy: &config.Policy{AllowedSchemes: []string{"file"}},
+		},
+		{
+			name:     "checksum file checked",
+			url:      "https://github.com/acme/tools/file.txt",
+			checksum: "https://evil.example.com/SHA256SUMS",
+			policy:   &config.Policy{AllowedHosts: []string{"github.com"}},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			downloads = 0
+			cfg := testConfig()
+			cfg.Retries = 2
+			f := New(cfg,
+				WithLogger(slog.New(slog.DiscardHandler)),
+				WithGetter("mem", &memGetter{}),
+				WithDetector(docsDetector{}),
+				WithDownloader(fake),
+				WithPolicy(tt.policy))
+
+			dest := filepath.Join(t.TempDir(), "out")
+			result, err := f.Fetch(context.Background(), config.Source{URL: tt.url, Dest: dest, Checksum: tt.checksum})
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("Fetch() unexpected error: %v", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, config.ErrPolicyDenied) {
+				t.Fatalf("Fetch() error = %v, want ErrPolicyDenied", err)
+			}
+			if result.Attempts != 1 || downloads != 0 {
+				t.Errorf("Attempts = %d, downloads = %d, want 1 attempt and no download", result.Attempts, downloads)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/go-getter/v2"
+	"github.com/universal-development/go-getter-file/internal/checksum"
+	"github.com/universal-development/go-getter-file/internal/config"
+)
+
+// Artifact describes the resolved state of a fetched source
+type Artifact struct {
+	ResolvedURL string
+	Commit      string
+	ETag        string
+	Size        int64
+	Hash        string
+}
+
+// ResolveURL runs the registered and go-getter detectors on a source URL and returns the
+// canonical form including the forced getter, e.g. github.com/org/repo
+// becomes git::https://github.com/org/repo.git
+func (f *Fetcher) ResolveURL(src string) (string, error) {
+	detected, err := f.detect(src)
+	if err != nil {
+		return "", err
+	}
+
+	for _, ng := range f.allGetters() {
+		req := &getter.Request{Src: detected, Pwd: f.pwd}
+		ok, err := getter.Detect(req, ng.getter)
+		if err != nil {
+			return "", fmt.Errorf("failed to detect getter for %s: %w", src, err)
+		}
+		if !ok {
+			continue
+		}
+
+		forced := req.Forced
+		if forced == "" {
+			forced = ng.name
+		}
+		if forced == "" {
+			return req.Src, nil
+		}
+		return forced + "::" + req.Src, nil
+	}
+
+	return "", fmt.Errorf("no getter available for %s", src)
+}
+
+// Inspect describes a source that has already been fetched to its destination
+func (f *Fetcher) Inspect(ctx context.Context, source config.Source) (*Artifact, error) {
+	resolved, err := f.ResolveURL(source.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	artifact := ArtifactPath(source)
+
+	hash, err := checksum.Sum(checksum.SHA256, artifact)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash %s: %w", artifact, err)
+	}
+
+	size, err := diskSize(artifact)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Artifact{
+		ResolvedURL: resolved,
+		Hash:        checksum.SHA256 + ":" + hash,
+		Size:        size,
+	}
+
+	forced, rawURL, _ := strings.Cut(resolved, "::")
+	switch forced {
+	case "git":
+		result.Commit = gitCommit(ctx, source.Dest, rawURL)
+	case "http":
+		result.ETag = httpETag(ctx, rawURL)
+	}
+
+	return result, nil
+}
+
+// getterName returns the forced getter prefix for a go-getter implementation
+func getterName(g getter.Getter) string {
+	switch g.(type) {
+	case *getter.GitGetter:
+		return "git"
+	case *getter.HgGetter:
+		return "hg"
+	case *getter.HttpGetter:
+		return "http"
+	case *getter.FileGetter:
+		return "file"
+	case *getter.SmbClientGetter, *getter.SmbMountGetter:
+		return "smb"
+	}
+	return ""
+}
+
+// diskSize returns the total size of the regular files under path, skipping
+// VCS metadata like the directory digest does
+func diskSize(path string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && p != path && checksum.IsVCSDir(d.Name()) {
+			return filepath.SkipDir
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to determine size of %s: %w", path, err)
+	}
+	return size, nil
+}
+
+// gitCommit returns the commit a git source resolved to. A clone kept in
+// dest is asked directly, otherwise the remote is queried for the ref.
+// Failures are not fatal because the content hash is locked as well.
+func gitCommit(ctx context.Context, dest, rawURL string) string {
+	if _, err := os.Stat(filepath.Join(dest, ".git")); err == nil {
+		out, err := exec.CommandContext(ctx, "git", "-C", dest, "rev-parse", "HEAD").Output()
+		if err == nil {
+			return strings.TrimSpace(string(out))
+		}
+	}
+
+	repo, _ := getter.SourceDirSubdir(rawURL)
+	u, err := url.Parse(repo)
+	if err != nil {
+		return ""
+	}
+	ref := u.Query().Get("ref")
+	if ref == "" {
+		ref = "HEAD"
+	}
+	u.RawQuery = ""
+
+	out, err := exec.CommandContext(ctx, "git", "ls-remote", u.String(), ref).Output()
+	if err != nil {
+		return ""
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) == 0 {
+		// The ref may already be a commit which ls-remote does not list
+		return ref
+	}
+	return fields[0]
+}
+
+// httpETag returns the ETag header the server reports for rawURL
+func httpETag(ctx context.Context, rawURL string) string {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, rawURL, nil)
+	if err != nil {
+		return ""
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return ""
+	}
+	resp.Body.Close()
+	return resp.Header.Get("ETag")
+}
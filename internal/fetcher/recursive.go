@@ -0,0 +1,285 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/go-getter/v2"
+	"github.com/universal-development/go-getter-file/internal/config"
+)
+
+// hrefPattern matches the link targets of anchors in a directory listing
+var hrefPattern = regexp.MustCompile(`(?i)<a\s[^>]*href\s*=\s*["']([^"']+)["']`)
+
+// crawlURL returns the listing URL of a source that is mirrored by crawling
+// HTTP directory listings, or an empty string for any other source
+func (f *Fetcher) crawlURL(source config.Source) string {
+	if source.Recursive == nil || !*source.Recursive {
+		return ""
+	}
+
+	resolved, err := f.ResolveURL(source.URL)
+	if err != nil {
+		return ""
+	}
+	forced, rawURL, ok := strings.Cut(resolved, "::")
+	if !ok || forced != "http" {
+		return ""
+	}
+	return rawURL
+}
+
+// crawlHTTP mirrors the files linked from the directory listing at rawURL
+// into dest, descending into linked subdirectories. Only links below
+// rawURL are followed, so parent directory and sort links are ignored.
+func crawlHTTP(ctx context.Context, rawURL, dest string, progress getter.ProgressTracker) error {
+	root, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid url %s: %w", rawURL, err)
+	}
+	if !strings.HasSuffix(root.Path, "/") {
+		root.Path += "/"
+	}
+	root.RawQuery = ""
+	root.Fragment = ""
+
+	c := &crawler{root: root, dest: dest, progress: progress, seen: map[string]bool{}}
+	if err := c.crawl(ctx, root); err != nil {
+		return err
+	}
+	if c.files == 0 {
+		return fmt.Errorf("no files found in directory listing %s", root)
+	}
+	return nil
+}
+
+// crawler holds the state of a single crawl
+type crawler struct {
+	root     *url.URL
+	dest     string
+	progress getter.ProgressTracker
+	seen     map[string]bool
+	files    int
+}
+
+// crawl downloads the files linked from the listing at dir and crawls its
+// subdirectories
+func (c *crawler) crawl(ctx context.Context, dir *url.URL) error {
+	c.seen[dir.String()] = true
+
+	resp, err := httpGet(ctx, dir.String())
+	if err != nil {
+		return err
+	}
+	listing, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read directory listing %s: %w", dir, err)
+	}
+
+	for _, match := range hrefPattern.FindAllStringSubmatch(string(listing), -1) {
+		link, ok := c.resolve(dir, match[1])
+		if !ok || c.seen[link.String()] {
+			continue
+		}
+
+		if strings.HasSuffix(link.Path, "/") {
+			if err := c.crawl(ctx, link); err != nil {
+				return err
+			}
+			continue
+		}
+
+		c.seen[link.String()] = true
+		if err := c.download(ctx, link); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resolve resolves href relative to dir and reports whether it points
+// below the crawl root
+func (c *crawler) resolve(dir *url.URL, href string) (*url.URL, bool) {
+	ref, err := url.Parse(strings.TrimSpace(href))
+	if err != nil || ref.RawQuery != "" {
+		return nil, false
+	}
+
+	link := dir.ResolveReference(ref)
+	link.Fragment = ""
+	if link.Scheme != c.root.Scheme || link.Host != c.root.Host {
+		return nil, false
+	}
+	if !strings.HasPrefix(link.Path, c.root.Path) || len(link.Path) <= len(c.root.Path) {
+		return nil, false
+	}
+	return link, true
+}
+
+// download saves a linked file below dest, mirroring its path below the root
+func (c *crawler) download(ctx context.Context, link *url.URL) error {
+	rel := filepath.FromSlash(strings.TrimPrefix(link.Path, c.root.Path))
+	if !filepath.IsLocal(rel) {
+		return fmt.Errorf("refusing to write %s outside of %s", link, c.dest)
+	}
+	target := filepath.Join(c.dest, rel)
+
+	resp, err := httpGet(ctx, link.String())
+	if err != nil {
+		return err
+	}
+	body := resp.Body
+	if c.progress != nil {
+		body = c.progress.TrackProgress(link.String(), 0, resp.ContentLength, body)
+	}
+	defer body.Close()
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, body); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to download %s: %w", link, err)
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	c.files++
+	return nil
+}
+
+// httpGet requests rawURL and returns the response if it was successful
+func httpGet(ctx context.Context, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", rawURL, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		resp.Body.Close()
+		return nil, &StatusError{URL: rawURL, StatusCode: resp.StatusCode}
+	}
+	return resp, nil
+}
+
+// applyRecursive enforces an explicit recursive setting on a downloaded
+// directory. go-getter symlinks local directories, so those are replaced
+// by a copy of the whole tree or of the top-level files only; subdirectories
+// of other downloads, including VCS metadata, are removed when recursive is
+// false.
+func applyRecursive(path string, recursive bool) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+
+	if info.Mode()&fs.ModeSymlink != 0 {
+		target, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			return err
+		}
+		targetInfo, err := os.Stat(target)
+		if err != nil {
+			return err
+		}
+		if !targetInfo.IsDir() {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		return copyDir(target, path, recursive)
+	}
+
+	if recursive || !info.IsDir() {
+		return nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			if err := os.RemoveAll(filepath.Join(path, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// copyDir copies the directory src to dst, descending into subdirectories
+// only when recursive is set. Symlinks are copied as links.
+func copyDir(src, dst string, recursive bool) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			if path != src && !recursive {
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case d.Type().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		}
+		return nil
+	})
+}
+
+// copyFile copies a regular file
+func copyFile(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
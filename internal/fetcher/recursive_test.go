@@ -0,0 +1,153 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/universal-development/go-getter-file/internal/config"
+)
+
+// newListingServer serves Apache style directory listings below /files/
+func newListingServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	pages := map[string]string{
+		"/files/": `<html><body><h1>Index of /files</h1>
+<a href="?C=N;O=D">Name</a>
+<a href="/">Parent Directory</a>
+<a href="a.txt">a.txt</a>
+<a href='sub/'>sub/</a>
+<a href="https://example.com/elsewhere.txt">elsewhere</a>
+</body></html>`,
+		"/files/sub/": `<html><body>
+<a href="../">Parent Directory</a>
+<a href="/files/sub/b.txt">b.txt</a>
+<a href="deeper/">deeper/</a>
+</body></html>`,
+		"/files/sub/deeper/":      `<a href="c.txt">c.txt</a>`,
+		"/files/a.txt":            "a",
+		"/files/sub/b.txt":        "b",
+		"/files/sub/deeper/c.txt": "c",
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, page)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFetchSourceRecursiveHTTP(t *testing.T) {
+	server := newListingServer(t)
+	dest := filepath.Join(t.TempDir(), "mirror")
+	recursive := true
+
+	f := New(config.Config{Timeout: 10 * time.Second}, WithLogger(slog.New(slog.DiscardHandler)))
+	err := f.FetchSource(context.Background(), config.Source{
+		URL:       server.URL + "/files/",
+		Dest:      dest,
+		Recursive: &recursive,
+	})
+	if err != nil {
+		t.Fatalf("FetchSource() unexpected error: %v", err)
+	}
+
+	for rel, want := range map[string]string{
+		"a.txt":            "a",
+		"sub/b.txt":        "b",
+		"sub/deeper/c.txt": "c",
+	} {
+		data, err := os.ReadFile(filepath.Join(dest, rel))
+		if err != nil || string(data) != want {
+			t.Errorf("%s = %q (%v), want %q", rel, string(data), err, want)
+		}
+	}
+
+	entries, err := os.ReadDir(dest)
+	if err != nil {
+		t.Fatalf("ReadDir() unexpected error: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("mirror contains %d entries, want a.txt and sub only", len(entries))
+	}
+}
+
+func TestFetchSourceRecursiveHTTPEmptyListing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<a href="../">Parent Directory</a>`)
+	}))
+	defer server.Close()
+
+	recursive := true
+	f := New(config.Config{Timeout: 10 * time.Second}, WithLogger(slog.New(slog.DiscardHandler)))
+	err := f.FetchSource(context.Background(), config.Source{
+		URL:       server.URL + "/empty/",
+		Dest:      filepath.Join(t.TempDir(), "mirror"),
+		Recursive: &recursive,
+	})
+	if err == nil {
+		t.Fatal("FetchSource() expected error for empty listing, got nil")
+	}
+}
+
+func TestFetchSourceRecursiveFile(t *testing.T) {
+	src := t.TempDir()
+	writeFile(t, filepath.Join(src, "top.txt"), "top")
+	writeFile(t, filepath.Join(src, "sub", "nested.txt"), "nested")
+
+	tests := []struct {
+		name       string
+		recursive  bool
+		wantNested bool
+	}{
+		{name: "recursive", recursive: true, wantNested: true},
+		{name: "top-level only", recursive: false, wantNested: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "out")
+			recursive := tt.recursive
+
+			f := New(config.Config{Timeout: 10 * time.Second}, WithLogger(slog.New(slog.DiscardHandler)))
+			err := f.FetchSource(context.Background(), config.Source{
+				URL:       src,
+				Dest:      dest,
+				Recursive: &recursive,
+			})
+			if err != nil {
+				t.Fatalf("FetchSource() unexpected error: %v", err)
+			}
+
+			info, err := os.Lstat(dest)
+			if err != nil || !info.IsDir() {
+				t.Fatalf("dest should be a copied directory, got %v (%v)", info, err)
+			}
+			if _, err := os.Stat(filepath.Join(dest, "top.txt")); err != nil {
+				t.Errorf("top.txt missing: %v", err)
+			}
+			_, err = os.Stat(filepath.Join(dest, "sub", "nested.txt"))
+			if tt.wantNested && err != nil {
+				t.Errorf("sub/nested.txt missing: %v", err)
+			}
+			if !tt.wantNested && err == nil {
+				t.Error("sub/nested.txt copied, want top-level files only")
+			}
+			if _, err := os.Stat(filepath.Join(src, "sub", "nested.txt")); err != nil {
+				t.Errorf("source directory was modified: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/universal-development/go-getter-file/internal/checksum"
+	"github.com/universal-development/go-getter-file/internal/config"
+)
+
+// StatusError is returned for HTTP responses with an unsuccessful status
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("failed to fetch %s: %d %s", e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// badResponsePattern matches the status errors of the go-getter http
+// getter, which are only available as text
+var badResponsePattern = regexp.MustCompile(`bad response code: (\d{3})`)
+
+// permanentMessages are fragments of errors that a retry cannot fix:
+// authentication failures of git and ssh, sources go-getter cannot parse,
+// resolve or find a getter for, and archive checksum mismatches reported by
+// an external go-getter binary
+var permanentMessages = []string{
+	"authentication failed",
+	"permission denied",
+	"could not read username",
+	"repository not found",
+	"error parsing url",
+	"invalid source string",
+	"unsupported protocol scheme",
+	"no getter available",
+	"relative paths require a module with a pwd",
+	"path traversal",
+	"checksums did not match",
+}
+
+// retryable reports whether a failed fetch may succeed when tried again.
+// Client errors other than timeouts and rate limiting, authentication
+// errors, invalid URLs and checksum mismatches are permanent.
+func retryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var mismatch *checksum.MismatchError
+	if errors.As(err, &mismatch) || errors.Is(err, ErrGetterNotAllowed) ||
+		errors.Is(err, config.ErrPolicyDenied) || errors.Is(err, config.ErrOutsideRoot) ||
+		errors.Is(err, config.ErrProtectedDest) ||
+		errors.Is(err, ErrUnsafeContent) || errors.Is(err, ErrVerifyFailed) ||
+		errors.Is(err, ErrForeignContent) {
+		return false
+	}
+
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) && urlErr.Op == "parse" {
+		return false
+	}
+
+	code := 0
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		code = statusErr.StatusCode
+	} else if m := badResponsePattern.FindStringSubmatch(err.Error()); m != nil {
+		code, _ = strconv.Atoi(m[1])
+	}
+	if code >= 400 && code < 500 {
+		return code == http.StatusRequestTimeout || code == http.StatusTooManyRequests
+	}
+
+	msg := strings.ToLower(err.Error())
+	if strings.HasPrefix(msg, "error downloading '") && strings.HasSuffix(msg, "'") {
+		// go-getter found no getter that accepts the source
+		return false
+	}
+	for _, fragment := range permanentMessages {
+		if strings.Contains(msg, fragment) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// backoffDelay returns the wait before the given retry, counted from one
+func backoffDelay(b config.Backoff, retry int) time.Duration {
+	initial, maxWait, multiplier, jitter := b.Values()
+	d := float64(initial) * math.Pow(math.Max(multiplier, 1), float64(retry-1))
+	if maxWait > 0 && d > float64(maxWait) {
+		d = float64(maxWait)
+	}
+	if jitter > 0 {
+		d += d * jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(d)
+}
+
+// wait blocks for d or until ctx is done
+func wait(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
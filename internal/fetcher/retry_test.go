@@ -0,0 +1,147 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/universal-development/go-getter-file/internal/checksum"
+	"github.com/universal-development/go-getter-file/internal/config"
+)
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "network error", err: errors.New("dial tcp: connection refused"), want: true},
+		{name: "timeout", err: context.DeadlineExceeded, want: true},
+		{name: "cancelled", err: fmt.Errorf("fetch: %w", context.Canceled), want: false},
+		{name: "server error", err: &StatusError{URL: "https://example.com", StatusCode: 503}, want: true},
+		{name: "not found", err: &StatusError{URL: "https://example.com", StatusCode: 404}, want: false},
+		{name: "request timeout", err: &StatusError{URL: "https://example.com", StatusCode: 408}, want: true},
+		{name: "rate limited", err: &StatusError{URL: "https://example.com", StatusCode: 429}, want: true},
+		{name: "go-getter not found", err: errors.New("go-getter failed for x: bad response code: 404"), want: false},
+		{name: "go-getter server error", err: errors.New("go-getter failed for x: bad response code: 502"), want: true},
+		{name: "checksum mismatch", err: fmt.Errorf("verify: %w", &checksum.MismatchError{Path: "x"}), want: false},
+		{name: "git auth", err: errors.New("fatal: Authentication failed for 'https://example.com/repo.git/'"), want: false},
+		{name: "ssh auth", err: errors.New("git@example.com: Permission denied (publickey)."), want: false},
+		{name: "no getter", err: errors.New("error downloading 'foo://bar'"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryable(tt.err); got != tt.want {
+				t.Errorf("retryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestBackoffDelay(t *testing.T) {
+	b := config.Backoff{Initial: ptr(100 * time.Millisecond), Max: ptr(time.Second), Multiplier: ptr(3.0)}
+
+	want := []time.Duration{100 * time.Millisecond, 300 * time.Millisecond, 900 * time.Millisecond, time.Second}
+	for i, w := range want {
+		if got := backoffDelay(b, i+1); got != w {
+			t.Errorf("backoffDelay(retry %d) = %v, want %v", i+1, got, w)
+		}
+	}
+
+	b.Jitter = ptr(0.5)
+	for i := 0; i < 100; i++ {
+		got := backoffDelay(b, 1)
+		if got < 50*time.Millisecond || got > 150*time.Millisecond {
+			t.Fatalf("backoffDelay() with jitter = %v, want within 50ms-150ms", got)
+		}
+	}
+}
+
+func TestFetchRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int
+		status       int
+		wantAttempts int
+		wantErr      bool
+	}{
+		{name: "transient errors are retried", failures: 2, status: http.StatusServiceUnavailable, wantAttempts: 3},
+		{name: "not found is permanent", failures: 5, status: http.StatusNotFound, wantAttempts: 1, wantErr: true},
+		{name: "retries are exhausted", failures: 5, status: http.StatusBadGateway, wantAttempts: 4, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodGet {
+					requests++
+				}
+				if requests <= tt.failures {
+					w.WriteHeader(tt.status)
+					return
+				}
+				fmt.Fprint(w, "hello")
+			}))
+			defer server.Close()
+
+			cfg := config.Config{
+				Retries: 3,
+				Timeout: 10 * time.Second,
+				Backoff: config.Backoff{Initial: ptr(time.Millisecond), Multiplier: ptr(2.0)},
+			}
+			result, err := New(cfg, WithLogger(slog.New(slog.DiscardHandler))).Fetch(context.Background(), config.Source{
+				URL:  server.URL + "/hello.txt",
+				Dest: filepath.Join(t.TempDir(), "out"),
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Fetch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if result.Attempts != tt.wantAttempts {
+				t.Errorf("Fetch() attempts = %d, want %d", result.Attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestFetchCancelDuringBackoff(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	cfg := config.Config{
+		Retries: 3,
+		Timeout: 10 * time.Second,
+		Backoff: config.Backoff{Initial: ptr(time.Minute)},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	result, err := New(cfg, WithLogger(slog.New(slog.DiscardHandler))).Fetch(ctx, config.Source{
+		URL:  server.URL + "/hello.txt",
+		Dest: filepath.Join(t.TempDir(), "out"),
+	})
+	if err == nil {
+		t.Fatal("Fetch() expected error, got nil")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Fetch() took %v, want the backoff wait to end with the context", elapsed)
+	}
+	if result.Attempts != 1 {
+		t.Errorf("Fetch() attempts = %d, want 1", result.Attempts)
+	}
+}
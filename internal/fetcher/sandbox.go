@@ -0,0 +1,86 @@
+package fetcher
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ErrUnsafeContent is returned for downloads containing symlinks that point
+// outside the download or special files like devices
+var ErrUnsafeContent = errors.New("unsafe content")
+
+// sandbox prepares a staged download for an output root: a symlink that
+// go-getter created for a local source is replaced with a copy of its
+// target, then the content is validated before it lands
+func sandbox(path string) error {
+	if err := materialize(path); err != nil {
+		return fmt.Errorf("failed to copy linked source into %s: %w", path, err)
+	}
+	return validateTree(path)
+}
+
+// materialize replaces a symlink at path with a copy of what it points to
+func materialize(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil || info.Mode()&fs.ModeSymlink == 0 {
+		return err
+	}
+
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+	targetInfo, err := os.Stat(target)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	if targetInfo.IsDir() {
+		return copyDir(target, path, true)
+	}
+	return copyFile(target, path, targetInfo.Mode().Perm())
+}
+
+// validateTree rejects content under root that could reach outside of it:
+// symlinks that, followed through every link on the way, resolve outside
+// root or to nothing, and special files. Targets are resolved on disk
+// rather than as text, as a chain of links that each stay inside root,
+// like s -> .. and t -> s/../.., can still lead out of it.
+func validateTree(root string) error {
+	resolvedRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return err
+	}
+
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir(), d.Type().IsRegular():
+			return nil
+		case d.Type()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			resolved, err := filepath.EvalSymlinks(path)
+			if err != nil {
+				return fmt.Errorf("%w: symlink %s to %s cannot be resolved: %v", ErrUnsafeContent, path, target, err)
+			}
+			rel, err := filepath.Rel(resolvedRoot, resolved)
+			if err != nil || !filepath.IsLocal(rel) {
+				return fmt.Errorf("%w: symlink %s points outside the download to %s", ErrUnsafeContent, path, target)
+			}
+			return nil
+		default:
+			return fmt.Errorf("%w: %s is a %s", ErrUnsafeContent, path, d.Type())
+		}
+	})
+}
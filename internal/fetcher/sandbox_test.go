@@ -0,0 +1,132 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/universal-development/go-getter-file/internal/config"
+)
+
+func TestValidateTree(t *testing.T) {
+	tests := []struct {
+		name    string
+		link    string
+		wantErr bool
+	}{
+		{name: "link inside", link: "docs/readme.md"},
+		{name: "link to root", link: "."},
+		{name: "relative link escaping", link: "../../etc/passwd", wantErr: true},
+		{name: "absolute link", link: "/etc/passwd", wantErr: true},
+		{name: "dangling link", link: "docs/missing.md", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := filepath.Join(t.TempDir(), "download")
+			if err := os.MkdirAll(filepath.Join(root, "docs"), 0755); err != nil {
+				t.Fatalf("Failed to create directory: %v", err)
+			}
+			if err := os.WriteFile(filepath.Join(root, "docs", "readme.md"), []byte("hello"), 0644); err != nil {
+				t.Fatalf("Failed to create file: %v", err)
+			}
+			if err := os.Symlink(tt.link, filepath.Join(root, "link")); err != nil {
+				t.Fatalf("Failed to create symlink: %v", err)
+			}
+
+			err := validateTree(root)
+			if tt.wantErr != errors.Is(err, ErrUnsafeContent) {
+				t.Errorf("validateTree() error = %v, want unsafe content: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateTreeChainedLinks(t *testing.T) {
+	// Each link stays inside the download as text, but following t through
+	// s leads two levels above it
+	root := filepath.Join(t.TempDir(), "a", "download")
+	if err := os.MkdirAll(filepath.Join(root, "sub"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.Symlink("..", filepath.Join(root, "sub", "s")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	if err := os.Symlink("s/../..", filepath.Join(root, "sub", "t")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	if err := validateTree(root); !errors.Is(err, ErrUnsafeContent) {
+		t.Errorf("validateTree() error = %v, want unsafe content", err)
+	}
+}
+
+func TestFetchSourceOutputRoot(t *testing.T) {
+	root := t.TempDir()
+	cfg := testConfig()
+	cfg.Retries = 2
+	cfg.OutputRoot = root
+
+	t.Run("dest outside the root", func(t *testing.T) {
+		f := New(cfg, WithLogger(slog.New(slog.DiscardHandler)), WithDownloader(DownloaderFunc(
+			func(ctx context.Context, source config.Source) error {
+				t.Error("downloader called for a dest outside the root")
+				return nil
+			})))
+
+		dest := filepath.Join(root, "..", "escaped")
+		result, err := f.Fetch(context.Background(), config.Source{URL: "https://example.com/file.txt", Dest: dest})
+		if !errors.Is(err, config.ErrOutsideRoot) {
+			t.Fatalf("Fetch() error = %v, want ErrOutsideRoot", err)
+		}
+		if result.Attempts != 1 {
+			t.Errorf("Attempts = %d, want 1", result.Attempts)
+		}
+	})
+
+	t.Run("download with an escaping symlink", func(t *testing.T) {
+		f := New(cfg, WithLogger(slog.New(slog.DiscardHandler)), WithDownloader(DownloaderFunc(
+			func(ctx context.Context, source config.Source) error {
+				if err := os.MkdirAll(source.Dest, 0755); err != nil {
+					return err
+				}
+				return os.Symlink("/etc", filepath.Join(source.Dest, "etc"))
+			})))
+
+		dest := filepath.Join(root, "repo")
+		_, err := f.Fetch(context.Background(), config.Source{URL: "https://example.com/repo.git", Dest: dest})
+		if !errors.Is(err, ErrUnsafeContent) {
+			t.Fatalf("Fetch() error = %v, want ErrUnsafeContent", err)
+		}
+		if _, err := os.Lstat(dest); !os.IsNotExist(err) {
+			t.Errorf("dest exists after unsafe content was rejected: %v", err)
+		}
+	})
+
+	t.Run("local directory is copied", func(t *testing.T) {
+		src := t.TempDir()
+		if err := os.WriteFile(filepath.Join(src, "file.txt"), []byte("hello"), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+
+		f := New(cfg, WithLogger(slog.New(slog.DiscardHandler)))
+		dest := filepath.Join(root, "local")
+		if err := f.FetchSource(context.Background(), config.Source{URL: src, Dest: dest}); err != nil {
+			t.Fatalf("FetchSource() unexpected error: %v", err)
+		}
+
+		info, err := os.Lstat(dest)
+		if err != nil {
+			t.Fatalf("Lstat() unexpected error: %v", err)
+		}
+		if info.Mode()&os.ModeSymlink != 0 || !info.IsDir() {
+			t.Errorf("dest mode = %v, want a copied directory", info.Mode())
+		}
+		if _, err := os.Stat(filepath.Join(dest, "file.txt")); err != nil {
+			t.Errorf("copied file missing: %v", err)
+		}
+	})
+}
@@ -0,0 +1,270 @@
+package fetcher
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ErrForeignContent is returned when committing a download would remove
+// content of the destination that the download did not produce
+var ErrForeignContent = errors.New("destination holds other content")
+
+// staging is a temporary sibling of a destination that downloads are
+// written to, so that the destination is only replaced once a download
+// completed and was verified
+type staging struct {
+	dir  string
+	path string
+	dest string
+}
+
+// newStaging creates a staging directory next to dest
+func newStaging(dest string) (*staging, error) {
+	parent := filepath.Dir(dest)
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create parent directory of %s: %w", dest, err)
+	}
+
+	dir, err := os.MkdirTemp(parent, "."+filepath.Base(dest)+".staging-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory for %s: %w", dest, err)
+	}
+
+	return &staging{
+		dir:  dir,
+		path: filepath.Join(dir, filepath.Base(dest)),
+		dest: dest,
+	}, nil
+}
+
+// commit moves the staged download into place. A staged directory is
+// merged into an existing destination directory: entries the previous
+// download produced that are no longer part of the download are removed,
+// staged entries replace entries of the same name and everything else in
+// the destination is kept, so content the download did not produce is
+// never removed. What a download produced is recorded in the manifest
+// next to the destination. Replaced and removed entries are kept in the
+// staging directory until all moves succeeded and are restored when one
+// fails.
+func (s *staging) commit() error {
+	info, err := os.Lstat(s.path)
+	if err != nil {
+		return fmt.Errorf("download did not produce %s: %w", s.path, err)
+	}
+
+	entries, err := listTree(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to list download for %s: %w", s.dest, err)
+	}
+	stale := s.stale(entries)
+
+	var moves []move
+	err = s.removeStale(stale, &moves)
+	if err == nil {
+		err = s.place(s.path, s.dest, &moves)
+	}
+	if err != nil {
+		if undoErr := undo(moves); undoErr != nil {
+			return fmt.Errorf("failed to move download into %s: %w (restoring previous content failed: %v)", s.dest, err, undoErr)
+		}
+		return fmt.Errorf("failed to move download into %s: %w", s.dest, err)
+	}
+
+	if !info.IsDir() {
+		if err := os.Remove(ManifestPath(s.dest)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove manifest of %s: %w", s.dest, err)
+		}
+		return nil
+	}
+	if err := os.WriteFile(ManifestPath(s.dest), []byte(strings.Join(entries, "\n")+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write manifest of %s: %w", s.dest, err)
+	}
+	return nil
+}
+
+// ManifestPath returns the path of the file that lists the entries the last
+// download produced in dest
+func ManifestPath(dest string) string {
+	return filepath.Join(filepath.Dir(dest), "."+filepath.Base(dest)+".manifest")
+}
+
+// listTree returns the slash separated paths below root, directories
+// with a trailing slash. A root that is not a directory has no entries.
+func listTree(root string) ([]string, error) {
+	var entries []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == root {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			rel += "/"
+		}
+		entries = append(entries, rel)
+		return nil
+	})
+	return entries, err
+}
+
+// stale returns the entries of the previous manifest that are not part of
+// entries, skipping entries that do not stay inside the destination
+func (s *staging) stale(entries []string) []string {
+	data, err := os.ReadFile(ManifestPath(s.dest))
+	if err != nil {
+		return nil
+	}
+
+	var stale []string
+	for _, entry := range strings.Split(string(data), "\n") {
+		if entry == "" || !filepath.IsLocal(filepath.FromSlash(strings.TrimSuffix(entry, "/"))) || slices.Contains(entries, entry) {
+			continue
+		}
+		stale = append(stale, entry)
+	}
+	return stale
+}
+
+// removeStale moves the stale files and symlinks of the destination to the
+// staging directory, followed by the stale directories that are empty then,
+// deepest first. Entries below a symlink are left alone, so nothing outside
+// the destination is touched.
+func (s *staging) removeStale(stale []string, moves *[]move) error {
+	stale = slices.Clone(stale)
+	slices.SortStableFunc(stale, func(a, b string) int {
+		return depth(b) - depth(a)
+	})
+
+	for _, entry := range stale {
+		path := filepath.Join(s.dest, filepath.FromSlash(strings.TrimSuffix(entry, "/")))
+		if !insideDir(s.dest, path) {
+			continue
+		}
+		info, err := os.Lstat(path)
+		if err != nil || info.IsDir() != strings.HasSuffix(entry, "/") {
+			continue
+		}
+		if info.IsDir() {
+			if entries, err := os.ReadDir(path); err != nil || len(entries) > 0 {
+				continue
+			}
+		}
+		backup := filepath.Join(s.dir, fmt.Sprintf(".previous-%d", len(*moves)))
+		if err := rename(path, backup, moves); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// depth orders manifest entries: files first, then directories by depth
+func depth(entry string) int {
+	if !strings.HasSuffix(entry, "/") {
+		return math.MaxInt
+	}
+	return strings.Count(entry, "/")
+}
+
+// insideDir reports whether dir and every parent of path below it are
+// directories rather than symlinks
+func insideDir(dir, path string) bool {
+	for parent := filepath.Dir(path); len(parent) >= len(dir); parent = filepath.Dir(parent) {
+		info, err := os.Lstat(parent)
+		if err != nil || !info.IsDir() {
+			return false
+		}
+		if parent == dir {
+			return true
+		}
+	}
+	return false
+}
+
+// move is a rename done while committing
+type move struct {
+	from string
+	to   string
+}
+
+// place moves src to dst, merging directories present in both and moving
+// replaced entries to the staging directory. Every rename is recorded in
+// moves.
+func (s *staging) place(src, dst string, moves *[]move) error {
+	srcInfo, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	dstInfo, err := os.Lstat(dst)
+	if os.IsNotExist(err) {
+		return rename(src, dst, moves)
+	}
+	if err != nil {
+		return err
+	}
+
+	if dstInfo.IsDir() {
+		if srcInfo.IsDir() {
+			entries, err := os.ReadDir(src)
+			if err != nil {
+				return err
+			}
+			for _, entry := range entries {
+				if err := s.place(filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name()), moves); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if entries, err := os.ReadDir(dst); err != nil || len(entries) > 0 {
+			return fmt.Errorf("%w: not replacing non-empty directory %s with %s", ErrForeignContent, dst, kind(srcInfo))
+		}
+	}
+
+	backup := filepath.Join(s.dir, fmt.Sprintf(".previous-%d", len(*moves)))
+	if err := rename(dst, backup, moves); err != nil {
+		return err
+	}
+	return rename(src, dst, moves)
+}
+
+// kind describes the type of a file for error messages
+func kind(info os.FileInfo) string {
+	if info.Mode()&os.ModeSymlink != 0 {
+		return "a symlink"
+	}
+	return "a file"
+}
+
+// rename renames from to to and records it in moves
+func rename(from, to string, moves *[]move) error {
+	if err := os.Rename(from, to); err != nil {
+		return err
+	}
+	*moves = append(*moves, move{from: from, to: to})
+	return nil
+}
+
+// undo reverts moves in reverse order
+func undo(moves []move) error {
+	var errs []error
+	for i := len(moves) - 1; i >= 0; i-- {
+		if err := os.Rename(moves[i].to, moves[i].from); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// cleanup removes the staging directory and everything left in it
+func (s *staging) cleanup() error {
+	return os.RemoveAll(s.dir)
+}
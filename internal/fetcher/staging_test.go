@@ -0,0 +1,223 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/universal-development/go-getter-file/internal/config"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+}
+
+func TestStagingCommit(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing map[string]string
+		previous map[string]string
+		staged   map[string]string
+		want     map[string]string
+		gone     []string
+		wantErr  bool
+	}{
+		{
+			name:   "new destination",
+			staged: map[string]string{"a.txt": "a"},
+			want:   map[string]string{"a.txt": "a"},
+		},
+		{
+			name:     "single file keeps other files",
+			existing: map[string]string{"a.txt": "old", "b.txt": "b"},
+			staged:   map[string]string{"a.txt": "new"},
+			want:     map[string]string{"a.txt": "new", "b.txt": "b"},
+		},
+		{
+			name:     "directory merges into non-empty destination",
+			existing: map[string]string{"a.txt": "old", "project.go.getter.yaml": "config", "sub/c.txt": "c"},
+			staged:   map[string]string{"a.txt": "a", "sub/b.txt": "b"},
+			want:     map[string]string{"a.txt": "a", "project.go.getter.yaml": "config", "sub/b.txt": "b", "sub/c.txt": "c"},
+		},
+		{
+			name:     "entries removed upstream are removed",
+			existing: map[string]string{"project.go.getter.yaml": "config"},
+			previous: map[string]string{"a.txt": "a", "old.txt": "old", "sub/b.txt": "b"},
+			staged:   map[string]string{"a.txt": "new"},
+			want:     map[string]string{"a.txt": "new", "project.go.getter.yaml": "config"},
+			gone:     []string{"old.txt", "sub"},
+		},
+		{
+			name:     "removed directory with other content is kept",
+			previous: map[string]string{"sub/b.txt": "b"},
+			existing: map[string]string{"sub/c.txt": "c"},
+			staged:   map[string]string{"a.txt": "a"},
+			want:     map[string]string{"a.txt": "a", "sub/c.txt": "c"},
+			gone:     []string{"sub/b.txt"},
+		},
+		{
+			name:     "file replaces previous directory download",
+			previous: map[string]string{"a.txt": "a", "sub/b.txt": "b"},
+			staged:   map[string]string{"": "file"},
+			want:     map[string]string{"": "file"},
+		},
+		{
+			name:     "file replaces file destination",
+			existing: map[string]string{"": "old"},
+			staged:   map[string]string{"": "new"},
+			want:     map[string]string{"": "new"},
+		},
+		{
+			name:     "file does not replace non-empty directory",
+			existing: map[string]string{"sub/c.txt": "c"},
+			staged:   map[string]string{"a.txt": "a", "sub": "file"},
+			want:     map[string]string{"sub/c.txt": "c"},
+			gone:     []string{"a.txt"},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "dest")
+			if tt.previous != nil {
+				commitStaged(t, dest, tt.previous)
+			}
+			for name, content := range tt.existing {
+				writeFile(t, filepath.Join(dest, name), content)
+			}
+
+			stage, err := newStaging(dest)
+			if err != nil {
+				t.Fatalf("newStaging() unexpected error: %v", err)
+			}
+			for name, content := range tt.staged {
+				writeFile(t, filepath.Join(stage.path, name), content)
+			}
+
+			err = stage.commit()
+			if tt.wantErr != errors.Is(err, ErrForeignContent) {
+				t.Fatalf("commit() error = %v, want foreign content error: %v", err, tt.wantErr)
+			}
+			if err := stage.cleanup(); err != nil {
+				t.Fatalf("cleanup() unexpected error: %v", err)
+			}
+
+			for name, content := range tt.want {
+				data, err := os.ReadFile(filepath.Join(dest, name))
+				if err != nil {
+					t.Errorf("Expected %s to exist: %v", name, err)
+					continue
+				}
+				if string(data) != content {
+					t.Errorf("%s = %q, want %q", name, string(data), content)
+				}
+			}
+			for _, name := range tt.gone {
+				if _, err := os.Stat(filepath.Join(dest, name)); !os.IsNotExist(err) {
+					t.Errorf("Expected %s to be removed", name)
+				}
+			}
+
+			assertNoStaging(t, filepath.Dir(dest))
+		})
+	}
+}
+
+func commitStaged(t *testing.T, dest string, files map[string]string) {
+	t.Helper()
+	stage, err := newStaging(dest)
+	if err != nil {
+		t.Fatalf("newStaging() unexpected error: %v", err)
+	}
+	defer stage.cleanup()
+	for name, content := range files {
+		writeFile(t, filepath.Join(stage.path, name), content)
+	}
+	if err := stage.commit(); err != nil {
+		t.Fatalf("commit() unexpected error: %v", err)
+	}
+}
+
+func TestFetchSourceFailureKeepsDestination(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	dest := filepath.Join(t.TempDir(), "out")
+	writeFile(t, filepath.Join(dest, "file.txt"), "previous")
+
+	f := New(config.Config{Timeout: 10 * time.Second})
+	err := f.FetchSource(context.Background(), config.Source{
+		URL:  server.URL + "/file.txt",
+		Dest: dest,
+	})
+	if err == nil {
+		t.Fatal("FetchSource() expected error, got nil")
+	}
+
+	data, err := os.ReadFile(filepath.Join(dest, "file.txt"))
+	if err != nil || string(data) != "previous" {
+		t.Errorf("Expected previous content to be kept, got %q (%v)", string(data), err)
+	}
+
+	assertNoStaging(t, filepath.Dir(dest))
+}
+
+func TestFetchSourceKeepsOtherFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	src := filepath.Join(tmpDir, "src")
+	writeFile(t, filepath.Join(src, "lib.txt"), "new")
+
+	dest := filepath.Join(tmpDir, "project")
+	writeFile(t, filepath.Join(dest, "lib.txt"), "old")
+	writeFile(t, filepath.Join(dest, "project.go.getter.yaml"), "config")
+
+	// Without an output root local directories are linked, and a link
+	// cannot be merged with the files already in dest
+	f := New(config.Config{Timeout: 10 * time.Second})
+	err := f.FetchSource(context.Background(), config.Source{URL: src, Dest: dest})
+	if !errors.Is(err, ErrForeignContent) {
+		t.Fatalf("FetchSource() error = %v, want ErrForeignContent", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dest, "lib.txt")); err != nil || string(data) != "old" {
+		t.Fatalf("lib.txt = %q (%v), want the destination untouched", data, err)
+	}
+
+	f = New(config.Config{Timeout: 10 * time.Second, OutputRoot: tmpDir})
+	if err := f.FetchSource(context.Background(), config.Source{URL: src, Dest: dest}); err != nil {
+		t.Fatalf("FetchSource() unexpected error: %v", err)
+	}
+
+	for name, want := range map[string]string{"lib.txt": "new", "project.go.getter.yaml": "config"} {
+		data, err := os.ReadFile(filepath.Join(dest, name))
+		if err != nil || string(data) != want {
+			t.Errorf("%s = %q (%v), want %q", name, data, err, want)
+		}
+	}
+	assertNoStaging(t, tmpDir)
+}
+
+func assertNoStaging(t *testing.T, dir string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read directory: %v", err)
+	}
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), ".staging-") {
+			t.Errorf("Staging directory %s was not cleaned up", entry.Name())
+		}
+	}
+}
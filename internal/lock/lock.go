@@ -0,0 +1,135 @@
+package lock
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/universal-development/go-getter-file/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// FormatVersion is the version of the lockfile format
+const FormatVersion = 1
+
+const header = "# Generated by go-getter-file lock. DO NOT EDIT.\n"
+
+// Lockfile records the resolved state of every source of a configuration file
+type Lockfile struct {
+	Version int     `yaml:"version"`
+	Name    string  `yaml:"name"`
+	Sources []Entry `yaml:"sources"`
+}
+
+// Entry represents the locked state of a single source
+type Entry struct {
+	URL         string `yaml:"url"`
+	Dest        string `yaml:"dest"`
+	ResolvedURL string `yaml:"resolved-url"`
+	Commit      string `yaml:"commit,omitempty"`
+	ETag        string `yaml:"etag,omitempty"`
+	Size        int64  `yaml:"size"`
+	Hash        string `yaml:"hash"`
+}
+
+// PathFor returns the lockfile path that belongs to a configuration file,
+// e.g. project.go.getter.yaml -> project.go.getter.lock. The extension of
+// every configuration format is replaced.
+func PathFor(configPath string) string {
+	ext := filepath.Ext(configPath)
+	if slices.Contains(config.Extensions(), strings.ToLower(ext)) {
+		configPath = strings.TrimSuffix(configPath, ext)
+	}
+	return configPath + ".lock"
+}
+
+// Load reads a lockfile from the given path
+func Load(path string) (*Lockfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lockfile %s: %w", path, err)
+	}
+
+	var lf Lockfile
+	if err := yaml.Unmarshal(data, &lf); err != nil {
+		return nil, fmt.Errorf("failed to parse lockfile %s: %w", path, err)
+	}
+
+	if lf.Version != FormatVersion {
+		return nil, fmt.Errorf("unsupported lockfile version %d in %s", lf.Version, path)
+	}
+
+	return &lf, nil
+}
+
+// Save writes the lockfile to the given path
+func (l *Lockfile) Save(path string) error {
+	var buf bytes.Buffer
+	buf.WriteString(header)
+
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(l); err != nil {
+		return fmt.Errorf("failed to encode lockfile %s: %w", path, err)
+	}
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("failed to encode lockfile %s: %w", path, err)
+	}
+
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write lockfile %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Find returns the entry locked for the given url and dest
+func (l *Lockfile) Find(url, dest string) (Entry, bool) {
+	for _, entry := range l.Sources {
+		if entry.URL == url && entry.Dest == dest {
+			return entry, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Verify compares fetched entries against the lockfile and reports every
+// source that is missing from the lock or differs from it
+func (l *Lockfile) Verify(actual []Entry) error {
+	var details []string
+
+	for _, got := range actual {
+		want, ok := l.Find(got.URL, got.Dest)
+		if !ok {
+			details = append(details, fmt.Sprintf("%s: not present in lockfile", got.URL))
+			continue
+		}
+		if diff := want.diff(got); diff != "" {
+			details = append(details, fmt.Sprintf("%s: %s", got.URL, diff))
+		}
+	}
+
+	if len(details) > 0 {
+		return fmt.Errorf("fetched sources differ from lockfile: %s", strings.Join(details, "; "))
+	}
+
+	return nil
+}
+
+// diff describes the first difference between a locked and a fetched entry
+func (e Entry) diff(got Entry) string {
+	switch {
+	case e.ResolvedURL != got.ResolvedURL:
+		return fmt.Sprintf("resolved url %s, locked %s", got.ResolvedURL, e.ResolvedURL)
+	case e.Commit != "" && got.Commit != "" && e.Commit != got.Commit:
+		return fmt.Sprintf("commit %s, locked %s", got.Commit, e.Commit)
+	case e.Hash != got.Hash:
+		return fmt.Sprintf("hash %s, locked %s", got.Hash, e.Hash)
+	case e.Size != got.Size:
+		return fmt.Sprintf("size %d, locked %d", got.Size, e.Size)
+	}
+	return ""
+}
@@ -0,0 +1,112 @@
+package lock
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPathFor(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "project.go.getter.yaml", want: "project.go.getter.lock"},
+		{path: "configs/project.go.getter.yml", want: "configs/project.go.getter.lock"},
+		{path: "project.go.getter.hcl", want: "project.go.getter.lock"},
+		{path: "project.conf", want: "project.conf.lock"},
+	}
+
+	for _, tt := range tests {
+		if got := PathFor(tt.path); got != tt.want {
+			t.Errorf("PathFor(%s) = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "project.go.getter.lock")
+
+	lf := &Lockfile{
+		Version: FormatVersion,
+		Name:    "project",
+		Sources: []Entry{
+			{
+				URL:         "github.com/org/repo",
+				Dest:        "vendor/repo",
+				ResolvedURL: "git::https://github.com/org/repo.git",
+				Commit:      "0123456789abcdef",
+				Size:        42,
+				Hash:        "sha256:abcd",
+			},
+		},
+	}
+
+	if err := lf.Save(path); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+
+	if loaded.Name != lf.Name {
+		t.Errorf("Name = %s, want %s", loaded.Name, lf.Name)
+	}
+	if len(loaded.Sources) != 1 || loaded.Sources[0] != lf.Sources[0] {
+		t.Errorf("Sources = %+v, want %+v", loaded.Sources, lf.Sources)
+	}
+}
+
+func TestLoadNotFound(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.lock")); err == nil {
+		t.Error("Load() expected error for missing lockfile, got nil")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	locked := Entry{
+		URL:         "https://example.com/file.txt",
+		Dest:        "out",
+		ResolvedURL: "http::https://example.com/file.txt",
+		Size:        5,
+		Hash:        "sha256:aaaa",
+	}
+	lf := &Lockfile{Version: FormatVersion, Name: "test", Sources: []Entry{locked}}
+
+	changed := locked
+	changed.Hash = "sha256:bbbb"
+
+	unknown := locked
+	unknown.URL = "https://example.com/other.txt"
+
+	etag := locked
+	etag.ETag = "\"new\""
+
+	tests := []struct {
+		name      string
+		actual    []Entry
+		wantError string
+	}{
+		{name: "unchanged", actual: []Entry{locked}},
+		{name: "etag ignored", actual: []Entry{etag}},
+		{name: "hash changed", actual: []Entry{changed}, wantError: "hash sha256:bbbb, locked sha256:aaaa"},
+		{name: "not locked", actual: []Entry{unknown}, wantError: "not present in lockfile"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := lf.Verify(tt.actual)
+			if tt.wantError == "" {
+				if err != nil {
+					t.Errorf("Verify() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("Verify() error = %v, want error containing %q", err, tt.wantError)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/universal-development/go-getter-file/internal/config"
+)
+
+// runCommands runs the hook commands of a source one after another through
+// the shell in dir, stopping at the first that fails
+func runCommands(ctx context.Context, logger *slog.Logger, dir string, src config.Source, commands []string) error {
+	dest, err := filepath.Abs(src.Dest)
+	if err != nil {
+		dest = src.Dest
+	}
+
+	for _, command := range commands {
+		cmd := shellCommand(ctx, command)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GO_GETTER_FILE_SOURCE_NAME="+src.Name,
+			"GO_GETTER_FILE_SOURCE_URL="+src.URL,
+			"GO_GETTER_FILE_SOURCE_DEST="+dest)
+
+		logger.Debug("running hook", "command", command)
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("hook %q failed: %w: %s", command, err, strings.TrimSpace(string(output)))
+		}
+	}
+	return nil
+}
+
+// shellCommand returns the command running command through the shell
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
@@ -0,0 +1,145 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/universal-development/go-getter-file/internal/config"
+)
+
+// Ways of handling sources whose destinations are the same or nested
+const (
+	// DestConflictsError fails the run before anything is fetched
+	DestConflictsError = "error"
+	// DestConflictsWarn logs a warning and fetches anyway
+	DestConflictsWarn = "warn"
+)
+
+// ErrDestConflict is wrapped by the errors of runs stopped by sources with
+// the same or nested destinations
+var ErrDestConflict = errors.New("destination conflict")
+
+// WithDestConflicts sets how sources with the same or nested destinations
+// are handled, DestConflictsError by default
+func WithDestConflicts(mode string) Option {
+	return func(p *Processor) {
+		p.destConflicts = mode
+	}
+}
+
+// destRef identifies the destination of a source
+type destRef struct {
+	config int
+	source int
+	dest   string
+}
+
+// destConflict is a pair of sources that would race for the same files
+type destConflict struct {
+	a, b    destRef
+	message string
+}
+
+// findConflicts returns every pair of sources, in the same or different
+// configurations, whose destinations are equal or nested in each other.
+// Configurations that failed to load are nil and skipped.
+func findConflicts(paths []string, configs []*config.FileConfig) []destConflict {
+	var refs []destRef
+	for i, cfg := range configs {
+		if cfg == nil {
+			continue
+		}
+		for j, source := range cfg.Sources {
+			dest, err := filepath.Abs(source.Dest)
+			if err != nil {
+				dest = filepath.Clean(source.Dest)
+			}
+			refs = append(refs, destRef{config: i, source: j, dest: dest})
+		}
+	}
+
+	describe := func(r destRef) string {
+		return fmt.Sprintf("%s source %d (%s)", paths[r.config], r.source, r.dest)
+	}
+
+	var conflicts []destConflict
+	for i, a := range refs {
+		for _, b := range refs[i+1:] {
+			var relation string
+			switch {
+			case a.dest == b.dest:
+				relation = "write the same destination"
+			case isWithin(a.dest, b.dest), isWithin(b.dest, a.dest):
+				relation = "have nested destinations"
+			default:
+				continue
+			}
+			conflicts = append(conflicts, destConflict{
+				a:       a,
+				b:       b,
+				message: fmt.Sprintf("%s and %s %s", describe(a), describe(b), relation),
+			})
+		}
+	}
+	return conflicts
+}
+
+// isWithin reports whether path is below dir
+func isWithin(path, dir string) bool {
+	return strings.HasPrefix(path, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))
+}
+
+// failConflicts completes the report of a run stopped by conflicts before
+// anything was fetched
+func (p *Processor) failConflicts(report *Report, configs []*config.FileConfig, loadErrs []error, conflicts []destConflict) (*Report, error) {
+	messages := make([]string, len(conflicts))
+	for i, c := range conflicts {
+		p.logger.Error("destination conflict", "conflict", c.message)
+		messages[i] = c.message
+	}
+
+	for i, err := range conflictErrors(loadErrs, conflicts) {
+		cr := &report.Configs[i]
+		cr.Path = p.configFiles[i]
+		if configs[i] != nil {
+			cr.Name = configs[i].Name
+		}
+		cr.Sources = []SourceReport{}
+		cr.Status = StatusFailed
+		cr.Error = errorText(err)
+		cr.Err = err
+	}
+
+	report.Status = StatusFailed
+	report.DurationMS = time.Since(report.Started).Milliseconds()
+	return report, fmt.Errorf("%w, nothing was fetched: %s", ErrDestConflict, strings.Join(messages, "; "))
+}
+
+// conflictErrors returns the error of every configuration of a run stopped
+// by conflicts: the conflicts it is involved in, its load error, or that
+// it was skipped
+func conflictErrors(loadErrs []error, conflicts []destConflict) []error {
+	messages := make([][]string, len(loadErrs))
+	for _, c := range conflicts {
+		messages[c.a.config] = append(messages[c.a.config], c.message)
+		if c.b.config != c.a.config {
+			messages[c.b.config] = append(messages[c.b.config], c.message)
+		}
+	}
+
+	errs := make([]error, len(loadErrs))
+	for i := range errs {
+		switch {
+		case loadErrs[i] != nil:
+			errs[i] = loadErrs[i]
+		case len(messages[i]) > 0:
+			errs[i] = fmt.Errorf("%w: %s", ErrDestConflict, strings.Join(messages[i], "; "))
+		default:
+			errs[i] = fmt.Errorf("not processed because of a %w in other configs", ErrDestConflict)
+		}
+	}
+	return errs
+}
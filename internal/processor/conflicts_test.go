@@ -0,0 +1,108 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/universal-development/go-getter-file/internal/config"
+)
+
+func TestFindConflicts(t *testing.T) {
+	configs := func(dests ...[]string) []*config.FileConfig {
+		var cfgs []*config.FileConfig
+		for _, d := range dests {
+			cfg := &config.FileConfig{}
+			for _, dest := range d {
+				cfg.Sources = append(cfg.Sources, config.Source{Dest: dest})
+			}
+			cfgs = append(cfgs, cfg)
+		}
+		return cfgs
+	}
+
+	tests := []struct {
+		name    string
+		configs []*config.FileConfig
+		want    int
+	}{
+		{name: "distinct", configs: configs([]string{"/out/a"}, []string{"/out/b", "/out/ab"}), want: 0},
+		{name: "same across configs", configs: configs([]string{"/out/a"}, []string{"/out/a"}), want: 1},
+		{name: "nested across configs", configs: configs([]string{"/out/a"}, []string{"/out/a/b"}), want: 1},
+		{name: "nested in one config", configs: configs([]string{"/out", "/out/a/b"}), want: 1},
+		{name: "sibling prefix is not nested", configs: configs([]string{"/out/a", "/out/a-b"}), want: 0},
+		{name: "failed config skipped", configs: []*config.FileConfig{nil, configs([]string{"/out/a"})[0]}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths := make([]string, len(tt.configs))
+			for i := range paths {
+				paths[i] = fmt.Sprintf("config%d.go.getter.yaml", i)
+			}
+			if got := findConflicts(paths, tt.configs); len(got) != tt.want {
+				t.Errorf("findConflicts() = %v, want %d conflicts", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessDestConflicts(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			requests.Add(1)
+		}
+		fmt.Fprint(w, "hello")
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	// one.txt is saved inside the vendor directory that holds two.txt
+	for name, dest := range map[string]string{"one": "vendor", "two": "vendor/two.txt", "three": "three.txt"} {
+		configData := fmt.Sprintf(`version: 1
+name: "%[1]s"
+sources:
+  - url: "%[2]s/%[1]s.txt"
+    dest: "%[3]s"
+`, name, server.URL, dest)
+		if err := os.WriteFile(filepath.Join(tmpDir, name+".go.getter.yaml"), []byte(configData), 0644); err != nil {
+			t.Fatalf("Failed to create config file: %v", err)
+		}
+	}
+
+	proc, err := New([]string{tmpDir}, WithLogger(slog.New(slog.DiscardHandler)))
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	report, err := proc.Process(context.Background())
+	if !errors.Is(err, ErrDestConflict) {
+		t.Fatalf("Process() error = %v, want ErrDestConflict", err)
+	}
+	if requests.Load() != 0 {
+		t.Errorf("requests = %d, want nothing fetched", requests.Load())
+	}
+	for _, cr := range report.Configs {
+		if cr.Status != StatusFailed || !errors.Is(cr.Err, ErrDestConflict) {
+			t.Errorf("config %s: status %s, error %v, want failed with a conflict", cr.Name, cr.Status, cr.Err)
+		}
+	}
+
+	proc, err = New([]string{tmpDir}, WithLogger(slog.New(slog.DiscardHandler)), WithDestConflicts(DestConflictsWarn))
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if _, err := proc.Process(context.Background()); err != nil {
+		t.Fatalf("Process() with warnings unexpected error: %v", err)
+	}
+	if requests.Load() != 3 {
+		t.Errorf("requests = %d, want all 3 sources fetched", requests.Load())
+	}
+}
@@ -0,0 +1,74 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/universal-development/go-getter-file/internal/config"
+	"github.com/universal-development/go-getter-file/internal/fetcher"
+)
+
+// ErrCleanWithoutRoot is returned by Clean for configurations without an
+// output root
+var ErrCleanWithoutRoot = errors.New("clean requires an output root, set output-root or pass --output-root")
+
+// LoadResult is the outcome of loading a single configuration file
+type LoadResult struct {
+	Path   string
+	Config *config.FileConfig
+	Err    error
+}
+
+// Load loads and validates all configuration files without fetching
+func (p *Processor) Load() []LoadResult {
+	results := make([]LoadResult, 0, len(p.configFiles))
+	for _, path := range p.configFiles {
+		cfg, err := p.loadConfig(path)
+		results = append(results, LoadResult{Path: path, Config: cfg, Err: err})
+	}
+	return results
+}
+
+// Clean removes the destinations of all sources and returns the paths that
+// were removed. Destinations that do not exist are skipped. Configurations
+// without an output root are refused, so only destinations confined to a
+// directory meant for downloads are removed. Like for every command,
+// destinations that are or contain the configuration file or the working
+// directory fail to load.
+func (p *Processor) Clean() ([]string, error) {
+	var removed []string
+	var details []string
+
+	for _, result := range p.Load() {
+		if result.Err != nil {
+			details = append(details, fmt.Sprintf("%s: %v", result.Path, result.Err))
+			continue
+		}
+		if result.Config.Config.OutputRoot == "" {
+			details = append(details, fmt.Sprintf("%s: %v", result.Path, ErrCleanWithoutRoot))
+			continue
+		}
+
+		for _, src := range result.Config.Sources {
+			if _, err := os.Lstat(src.Dest); os.IsNotExist(err) {
+				continue
+			}
+			if err := os.RemoveAll(src.Dest); err != nil {
+				details = append(details, fmt.Sprintf("%s: %v", src.Dest, err))
+				continue
+			}
+			if err := os.Remove(fetcher.ManifestPath(src.Dest)); err != nil && !os.IsNotExist(err) {
+				details = append(details, fmt.Sprintf("%s: %v", src.Dest, err))
+			}
+			removed = append(removed, src.Dest)
+		}
+	}
+
+	if len(details) > 0 {
+		return removed, fmt.Errorf("failed to clean some destinations: %s", strings.Join(details, "; "))
+	}
+
+	return removed, nil
+}
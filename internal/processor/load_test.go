@@ -0,0 +1,86 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/universal-development/go-getter-file/internal/config"
+	"github.com/universal-development/go-getter-file/internal/fetcher"
+)
+
+func TestClean(t *testing.T) {
+	tmpDir := t.TempDir()
+	configsDir := filepath.Join(tmpDir, "configs")
+	vendor := filepath.Join(configsDir, "vendor")
+	if err := os.MkdirAll(vendor, 0755); err != nil {
+		t.Fatalf("Failed to create vendor directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(vendor, "file.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatalf("Failed to create vendored file: %v", err)
+	}
+	if err := os.WriteFile(fetcher.ManifestPath(vendor), []byte("file.txt\n"), 0644); err != nil {
+		t.Fatalf("Failed to create manifest: %v", err)
+	}
+
+	write := func(name, header, dest string) string {
+		path := filepath.Join(configsDir, name)
+		data := fmt.Sprintf(`version: 1
+name: %q
+%s
+sources:
+  - url: "https://example.com/file.txt"
+    dest: %q
+`, name, header, dest)
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatalf("Failed to create config file: %v", err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name      string
+		header    string
+		dest      string
+		wantError error
+	}{
+		{name: "no-root.go.getter.yaml", dest: "vendor", wantError: ErrCleanWithoutRoot},
+		{name: "config-dir.go.getter.yaml", header: "config:\n  output-root: " + tmpDir, dest: ".", wantError: config.ErrProtectedDest},
+		{name: "vendor.go.getter.yaml", header: "config:\n  output-root: " + configsDir, dest: "vendor"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proc, err := New([]string{write(tt.name, tt.header, tt.dest)})
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+
+			removed, err := proc.Clean()
+			if tt.wantError != nil {
+				if err == nil || !strings.Contains(err.Error(), tt.wantError.Error()) || len(removed) != 0 {
+					t.Fatalf("Clean() = %v, %v, want nothing removed and %v", removed, err, tt.wantError)
+				}
+				if _, err := os.Stat(filepath.Join(vendor, "file.txt")); err != nil {
+					t.Errorf("Expected the vendored file to be kept: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Clean() unexpected error: %v", err)
+			}
+			if len(removed) != 1 || removed[0] != vendor {
+				t.Errorf("Clean() removed %v, want %s", removed, vendor)
+			}
+			if _, err := os.Stat(vendor); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("Expected %s to be removed, got %v", vendor, err)
+			}
+			if _, err := os.Stat(fetcher.ManifestPath(vendor)); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("Expected the manifest of %s to be removed, got %v", vendor, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package processor
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/universal-development/go-getter-file/internal/checksum"
+	"github.com/universal-development/go-getter-file/internal/config"
+	"github.com/universal-development/go-getter-file/internal/fetcher"
+	"github.com/universal-development/go-getter-file/internal/lock"
+)
+
+// Plan statuses of a source
+const (
+	PlanMissing   = "missing"
+	PlanUnchanged = "unchanged"
+	PlanOverwrite = "overwrite"
+)
+
+// ConfigPlan describes what processing a configuration file would change
+type ConfigPlan struct {
+	Path    string       `json:"path"`
+	Name    string       `json:"name"`
+	Sources []SourcePlan `json:"sources"`
+}
+
+// SourcePlan describes what fetching a single source would change
+type SourcePlan struct {
+	URL    string `json:"url"`
+	Dest   string `json:"dest"`
+	Status string `json:"status"`
+}
+
+// Plan loads all configuration files and reports for every source whether
+// its destination is missing, unchanged or would be overwritten, without
+// downloading anything. A destination is only reported as unchanged when
+// it can be verified against an inline checksum or the lockfile.
+func (p *Processor) Plan() ([]ConfigPlan, error) {
+	var plans []ConfigPlan
+	var details []string
+
+	for _, path := range p.configFiles {
+		cfg, err := p.loadConfig(path)
+		if err != nil {
+			details = append(details, fmt.Sprintf("%s: %v", path, err))
+			continue
+		}
+
+		// Without a lockfile sources can only be verified by checksum
+		lf, _ := lock.Load(lock.PathFor(path))
+
+		plan := ConfigPlan{Path: path, Name: cfg.Name}
+		for _, src := range cfg.Sources {
+			plan.Sources = append(plan.Sources, SourcePlan{
+				URL:    src.URL,
+				Dest:   src.Dest,
+				Status: planSource(src, lockDest(path, src.Dest), lf),
+			})
+		}
+		plans = append(plans, plan)
+	}
+
+	if len(details) > 0 {
+		return plans, fmt.Errorf("some configuration files failed to load: %s", strings.Join(details, "; "))
+	}
+
+	return plans, nil
+}
+
+// planSource determines the plan status of a single source
+func planSource(src config.Source, lockedDest string, lf *lock.Lockfile) string {
+	if _, err := os.Stat(src.Dest); err != nil {
+		return PlanMissing
+	}
+
+	artifact := fetcher.ArtifactPath(src)
+
+	if src.Checksum != "" {
+		expected, err := checksum.Parse(src.Checksum)
+		if err == nil && expected.URL == "" && expected.Verify(artifact) == nil {
+			return PlanUnchanged
+		}
+	}
+
+	if lf != nil {
+		if entry, ok := lf.Find(src.URL, lockedDest); ok {
+			sum, err := checksum.Sum(checksum.SHA256, artifact)
+			if err == nil && entry.Hash == checksum.SHA256+":"+sum {
+				return PlanUnchanged
+			}
+		}
+	}
+
+	return PlanOverwrite
+}
+
+// WritePlan prints plans in a human readable form
+func WritePlan(w io.Writer, plans []ConfigPlan) {
+	counts := make(map[string]int)
+
+	for _, plan := range plans {
+		fmt.Fprintf(w, "\n==> Plan for config: %s (%s)\n", plan.Path, plan.Name)
+		for _, src := range plan.Sources {
+			counts[src.Status]++
+			fmt.Fprintf(w, "  %-10s %s -> %s\n", src.Status, src.URL, src.Dest)
+		}
+	}
+
+	fmt.Fprintf(w, "\nPlan: %d to fetch, %d to overwrite, %d unchanged\n",
+		counts[PlanMissing], counts[PlanOverwrite], counts[PlanUnchanged])
+}
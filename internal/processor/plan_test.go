@@ -0,0 +1,64 @@
+package processor
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	verified := filepath.Join(tmpDir, "verified.txt")
+	modified := filepath.Join(tmpDir, "modified.txt")
+	for _, file := range []string{verified, modified} {
+		if err := os.WriteFile(file, []byte("hello"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	configFile := filepath.Join(tmpDir, "plan.go.getter.yaml")
+	configData := fmt.Sprintf(`version: 1
+name: "plan"
+sources:
+  - url: "https://example.com/missing.txt"
+    dest: "%s"
+  - url: "https://example.com/verified.txt"
+    dest: "%s"
+    checksum: "sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+  - url: "https://example.com/modified.txt"
+    dest: "%s"
+`, filepath.Join(tmpDir, "missing.txt"), verified, modified)
+	if err := os.WriteFile(configFile, []byte(configData), 0644); err != nil {
+		t.Fatalf("Failed to create config file: %v", err)
+	}
+
+	proc, err := New([]string{configFile})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	plans, err := proc.Plan()
+	if err != nil {
+		t.Fatalf("Plan() unexpected error: %v", err)
+	}
+	if len(plans) != 1 || len(plans[0].Sources) != 3 {
+		t.Fatalf("Plan() returned %+v, want 1 config with 3 sources", plans)
+	}
+
+	want := []string{PlanMissing, PlanUnchanged, PlanOverwrite}
+	for i, src := range plans[0].Sources {
+		if src.Status != want[i] {
+			t.Errorf("source %d status = %s, want %s", i, src.Status, want[i])
+		}
+	}
+
+	var buf bytes.Buffer
+	WritePlan(&buf, plans)
+	if !strings.Contains(buf.String(), "Plan: 1 to fetch, 1 to overwrite, 1 unchanged") {
+		t.Errorf("WritePlan() output missing summary: %s", buf.String())
+	}
+}
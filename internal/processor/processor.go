@@ -3,22 +3,155 @@ package processor
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/hashicorp/go-getter/v2"
 	"github.com/universal-development/go-getter-file/internal/config"
 	"github.com/universal-development/go-getter-file/internal/fetcher"
+	"github.com/universal-development/go-getter-file/internal/lock"
 )
 
 // Processor handles processing configuration files
 type Processor struct {
 	configFiles []string
+	preloaded   map[string]*config.FileConfig
+	hooks       Hooks
+	writeLock   bool
+	locked      bool
+	overrides   config.Overrides
+	vars        map[string]string
+	destFromCwd bool
+	maxFetches  int
+	sched       *scheduler
+	progress    getter.ProgressTracker
+	logger      *slog.Logger
+	fetcherOpts []fetcher.Option
+	policy      *config.Policy
+	// destConflicts is DestConflictsError or DestConflictsWarn
+	destConflicts string
+}
+
+// Option configures a Processor
+type Option func(*Processor)
+
+// Hooks are called around every fetch. They are called concurrently for
+// sources that are fetched in parallel.
+type Hooks struct {
+	// BeforeFetch is called before a source is fetched. Returning an error
+	// fails the source without fetching it.
+	BeforeFetch func(ctx context.Context, configName string, source config.Source) error
+	// AfterFetch is called with the report of every source
+	AfterFetch func(ctx context.Context, configName string, report SourceReport)
+}
+
+// WithConfig adds a configuration that was loaded already, for example from
+// memory. It is reported under name, and its destinations are used as they
+// are, so relative destinations must have been resolved by the caller.
+func WithConfig(name string, cfg *config.FileConfig) Option {
+	return func(p *Processor) {
+		if p.preloaded == nil {
+			p.preloaded = make(map[string]*config.FileConfig)
+		}
+		p.preloaded[name] = cfg
+		p.configFiles = append(p.configFiles, name)
+	}
+}
+
+// WithHooks sets the hooks called around every fetch
+func WithHooks(h Hooks) Option {
+	return func(p *Processor) {
+		p.hooks = h
+	}
+}
+
+// WithWriteLock makes the processor write a lockfile next to every
+// configuration file after its sources were fetched successfully
+func WithWriteLock() Option {
+	return func(p *Processor) {
+		p.writeLock = true
+	}
+}
+
+// WithLocked makes the processor fail when a fetched source differs from
+// the lockfile of its configuration file
+func WithLocked() Option {
+	return func(p *Processor) {
+		p.locked = true
+	}
+}
+
+// WithOverrides sets values that take precedence over the global
+// configuration of every configuration file
+func WithOverrides(o config.Overrides) Option {
+	return func(p *Processor) {
+		p.overrides = o
+	}
+}
+
+// WithVars sets variables that take precedence over the vars section of
+// every configuration file
+func WithVars(vars map[string]string) Option {
+	return func(p *Processor) {
+		p.vars = vars
+	}
+}
+
+// WithDestFromCwd resolves relative destinations against the working
+// directory instead of the directory of the configuration file, which was
+// the behaviour of earlier releases
+func WithDestFromCwd() Option {
+	return func(p *Processor) {
+		p.destFromCwd = true
+	}
+}
+
+// WithMaxConcurrency limits the number of fetches running at once across
+// all configuration files, on top of the parallelism of each config. Slots
+// are shared fairly between configs. A value below one means no limit.
+func WithMaxConcurrency(n int) Option {
+	return func(p *Processor) {
+		p.maxFetches = n
+	}
+}
+
+// WithProgress reports the progress of every download to tracker
+func WithProgress(tracker getter.ProgressTracker) Option {
+	return func(p *Processor) {
+		p.progress = tracker
+	}
+}
+
+// WithLogger sets the logger progress messages are logged to
+func WithLogger(l *slog.Logger) Option {
+	return func(p *Processor) {
+		p.logger = l
+	}
+}
+
+// WithPolicy checks the sources of every configuration against a global
+// policy, in addition to the policy section of each configuration
+func WithPolicy(policy *config.Policy) Option {
+	return func(p *Processor) {
+		p.policy = policy
+	}
+}
+
+// WithFetcherOptions passes options to the fetcher of every configuration,
+// e.g. to register custom getters
+func WithFetcherOptions(opts ...fetcher.Option) Option {
+	return func(p *Processor) {
+		p.fetcherOpts = append(p.fetcherOpts, opts...)
+	}
 }
 
 // New creates a new Processor
-func New(paths []string) (*Processor, error) {
+func New(paths []string, opts ...Option) (*Processor, error) {
 	var configFiles []string
 
 	for _, path := range paths {
@@ -29,13 +162,25 @@ func New(paths []string) (*Processor, error) {
 		configFiles = append(configFiles, files...)
 	}
 
-	if len(configFiles) == 0 {
+	p := &Processor{
+		configFiles: configFiles,
+		logger:      slog.Default(),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	p.sched = newScheduler(p.maxFetches)
+
+	if len(p.configFiles) == 0 {
 		return nil, fmt.Errorf("no configuration files found")
 	}
 
-	return &Processor{
-		configFiles: configFiles,
-	}, nil
+	return p, nil
+}
+
+// ConfigFiles returns the configuration files the paths expanded to
+func (p *Processor) ConfigFiles() []string {
+	return slices.Clone(p.configFiles)
 }
 
 // expandPath expands a path to a list of configuration files
@@ -50,77 +195,316 @@ func expandPath(path string) ([]string, error) {
 		return []string{path}, nil
 	}
 
-	// If it's a directory, scan for *.go.getter.yaml files
-	pattern := filepath.Join(path, "*.go.getter.yaml")
-	matches, err := filepath.Glob(pattern)
-	if err != nil {
-		return nil, err
+	// If it's a directory, scan for *.go.getter.<ext> files of every format
+	var matches []string
+	for _, ext := range config.Extensions() {
+		files, err := filepath.Glob(filepath.Join(path, "*.go.getter"+ext))
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, files...)
 	}
 
 	if len(matches) == 0 {
-		return nil, fmt.Errorf("no *.go.getter.yaml files found in directory %s", path)
+		return nil, fmt.Errorf("no *.go.getter.{%s} files found in directory %s", strings.Join(extNames(), ","), path)
 	}
 
+	slices.Sort(matches)
 	return matches, nil
 }
 
-// Process processes all configuration files
-func (p *Processor) Process(ctx context.Context) error {
-	fmt.Printf("Processing %d configuration file(s)\n", len(p.configFiles))
+// extNames returns the configuration file extensions without their dot
+func extNames() []string {
+	exts := config.Extensions()
+	for i, ext := range exts {
+		exts[i] = strings.TrimPrefix(ext, ".")
+	}
+	return exts
+}
 
-	var wg sync.WaitGroup
+// Process processes all configuration files and returns a report of every
+// config and source. The report is returned on failure as well.
+func (p *Processor) Process(ctx context.Context) (*Report, error) {
+	p.logger.Info("processing configuration files", "count", len(p.configFiles))
+
+	report := &Report{
+		Started: time.Now(),
+		Configs: make([]ConfigReport, len(p.configFiles)),
+	}
+
+	// Load every configuration first so destinations can be checked
+	// across configurations before anything is fetched
+	configs := make([]*config.FileConfig, len(p.configFiles))
 	errors := make([]error, len(p.configFiles))
+	for i, configFile := range p.configFiles {
+		configs[i], errors[i] = p.loadConfig(configFile)
+	}
 
+	conflicts := findConflicts(p.configFiles, configs)
+	if len(conflicts) > 0 && p.destConflicts != DestConflictsWarn {
+		return p.failConflicts(report, configs, errors, conflicts)
+	}
+	for _, c := range conflicts {
+		p.logger.Warn("destination conflict", "conflict", c.message)
+	}
+
+	var wg sync.WaitGroup
 	for i, configFile := range p.configFiles {
 		wg.Add(1)
 		go func(idx int, file string) {
 			defer wg.Done()
-			errors[idx] = p.processConfigFile(ctx, file)
+			start := time.Now()
+			cr := &report.Configs[idx]
+			cr.Path = file
+			if configs[idx] != nil {
+				errors[idx] = p.processConfigFile(ctx, file, configs[idx], cr)
+			}
+			if cr.Sources == nil {
+				cr.Sources = []SourceReport{}
+			}
+			cr.Status = status(errors[idx])
+			cr.Error = errorText(errors[idx])
+			cr.Err = errors[idx]
+			cr.DurationMS = time.Since(start).Milliseconds()
 		}(i, configFile)
 	}
 
 	wg.Wait()
+	report.DurationMS = time.Since(report.Started).Milliseconds()
 
 	// Check for errors
 	var hasError bool
 	var details []string
 	for i, err := range errors {
 		if err != nil {
-			fmt.Printf("Error processing %s: %v\n", p.configFiles[i], err)
+			p.logger.Error("failed to process config", "path", p.configFiles[i], "error", err)
 			hasError = true
 			details = append(details, fmt.Sprintf("%s: %v", p.configFiles[i], err))
 		}
 	}
 
 	if hasError {
-		return fmt.Errorf("some configuration files failed to process: %s", strings.Join(details, "; "))
+		report.Status = StatusFailed
+		return report, fmt.Errorf("some configuration files failed to process: %s", strings.Join(details, "; "))
 	}
 
-	return nil
+	report.Status = StatusSuccess
+	return report, nil
 }
 
-// processConfigFile processes a single configuration file
-func (p *Processor) processConfigFile(ctx context.Context, path string) error {
-	fmt.Printf("\n==> Processing config: %s\n", path)
+// loadConfig loads a configuration file, applies the overrides and
+// resolves relative destinations
+func (p *Processor) loadConfig(path string) (*config.FileConfig, error) {
+	if pre, ok := p.preloaded[path]; ok {
+		cfg := *pre
+		p.overrides.Apply(&cfg.Config)
+		if cfg.Config.Parallelism < 1 {
+			return nil, fmt.Errorf("invalid config %s: parallelism must be at least 1", path)
+		}
+		if err := confineDests(&cfg, ""); err != nil {
+			return nil, fmt.Errorf("invalid config %s: %w", path, err)
+		}
+		return &cfg, nil
+	}
 
-	cfg, err := config.LoadConfig(path)
+	cfg, err := config.LoadConfig(path, config.WithVars(p.vars), config.WithPolicy(p.policy))
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	p.overrides.Apply(&cfg.Config)
+	if cfg.Config.Parallelism < 1 {
+		return nil, fmt.Errorf("invalid config file %s: parallelism must be at least 1", path)
 	}
 
-	fmt.Printf("Config: %s (version: %d)\n", cfg.Name, cfg.Version)
-	fmt.Printf("Sources: %d, Parallelism: %d, Retries: %d\n",
-		len(cfg.Sources), cfg.Config.Parallelism, cfg.Config.Retries)
+	root := filepath.Dir(path)
+	if p.destFromCwd {
+		root = ""
+	}
+	cfg.ResolveDests(root)
+	if err := confineDests(cfg, path); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// confineDests checks that every destination is inside the output root and
+// neither is nor contains the directory of the configuration file at path
+// or the working directory, so no command writes or removes anything
+// outside of it. path is empty for preloaded configurations.
+func confineDests(cfg *config.FileConfig, path string) error {
+	for i, source := range cfg.Sources {
+		if err := cfg.Config.ConfineDest(source.Dest); err != nil {
+			return fmt.Errorf("source %d: %w", i, err)
+		}
+		if err := config.CheckDest(source.Dest, path); err != nil {
+			return fmt.Errorf("source %d: %w", i, err)
+		}
+	}
+	return nil
+}
 
-	f := fetcher.New(cfg.Config)
+// processConfigFile processes a single configuration file and records the
+// outcome of its sources in report
+func (p *Processor) processConfigFile(ctx context.Context, path string, cfg *config.FileConfig, report *ConfigReport) error {
+	p.logger.Info("processing config", "path", path)
+
+	report.Name = cfg.Name
+	report.Sources = make([]SourceReport, len(cfg.Sources))
+
+	logger := p.logger.With("config", cfg.Name)
+	logger.Info("loaded config", "path", path, "version", cfg.Version, "sources", len(cfg.Sources),
+		"parallelism", cfg.Config.Parallelism, "retries", cfg.Config.Retries)
+
+	// Hook commands run and relative local sources are resolved next to
+	// the configuration file
+	var dir string
+	if _, ok := p.preloaded[path]; !ok {
+		dir = filepath.Dir(path)
+	}
+
+	fetcherOpts := []fetcher.Option{fetcher.WithLogger(logger), fetcher.WithPwd(dir)}
+	if p.progress != nil {
+		fetcherOpts = append(fetcherOpts, fetcher.WithProgress(p.progress))
+	}
+	fetcherOpts = append(fetcherOpts, fetcher.WithPolicy(p.policy), fetcher.WithPolicy(cfg.Policy))
+	fetcherOpts = append(fetcherOpts, p.fetcherOpts...)
+
+	// Lock entries describe the staged download, so files in a destination
+	// that the download did not produce never end up in them. In locked
+	// mode every download is checked against the lockfile before it
+	// replaces its destination.
+	var f *fetcher.Fetcher
+	var lf *lock.Lockfile
+	var entriesMu sync.Mutex
+	entries := make(map[string]lock.Entry)
+	lockPath := lock.PathFor(path)
+	if p.locked {
+		var err error
+		if lf, err = lock.Load(lockPath); err != nil {
+			return err
+		}
+	}
+	if p.locked || p.writeLock {
+		fetcherOpts = append(fetcherOpts, fetcher.WithVerify(func(ctx context.Context, src config.Source, stagedDest string) error {
+			entry, err := lockEntry(ctx, f, path, src, stagedDest)
+			if err != nil {
+				return err
+			}
+			if lf != nil {
+				if err := lf.Verify([]lock.Entry{entry}); err != nil {
+					return err
+				}
+			}
+			entriesMu.Lock()
+			entries[src.Dest] = entry
+			entriesMu.Unlock()
+			return nil
+		}))
+	}
+	f = fetcher.New(cfg.Config, fetcherOpts...)
 
 	// Process sources with parallelism
-	return p.processSources(ctx, f, cfg.Sources, cfg.Config.Parallelism)
+	if err := p.processSources(ctx, logger, f, cfg.Name, dir, cfg.Sources, cfg.Config.Parallelism, report.Sources); err != nil {
+		return err
+	}
+
+	if p.locked {
+		logger.Info("verified sources against lockfile", "path", lockPath)
+	}
+	if p.writeLock {
+		return writeLockfile(logger, path, cfg, entries)
+	}
+
+	return nil
+}
+
+// lockDest returns dest relative to the directory of the configuration
+// file so lockfiles do not depend on where the repository is checked out
+func lockDest(configPath, dest string) string {
+	dir, err := filepath.Abs(filepath.Dir(configPath))
+	if err != nil {
+		return dest
+	}
+	abs, err := filepath.Abs(dest)
+	if err != nil {
+		return dest
+	}
+	rel, err := filepath.Rel(dir, abs)
+	if err != nil {
+		return dest
+	}
+	return filepath.ToSlash(rel)
 }
 
-// processSources processes all sources with the specified parallelism
-func (p *Processor) processSources(ctx context.Context, f *fetcher.Fetcher, sources []config.Source, parallelism int) error {
+// lockURL returns the resolved url of a relative local source relative to
+// the directory of the configuration file, like lockDest
+func lockURL(configPath, url, resolved string) string {
+	local, ok := strings.CutPrefix(resolved, "file::")
+	if !ok || filepath.IsAbs(url) || strings.Contains(url, "::") || strings.Contains(url, "://") {
+		return resolved
+	}
+	return "file::" + lockDest(configPath, local)
+}
+
+// lockEntry describes the content of src fetched to dir as the lock entry
+// of the configuration file at path
+func lockEntry(ctx context.Context, f *fetcher.Fetcher, path string, src config.Source, dir string) (lock.Entry, error) {
+	fetched := src
+	fetched.Dest = dir
+	artifact, err := f.Inspect(ctx, fetched)
+	if err != nil {
+		return lock.Entry{}, fmt.Errorf("failed to inspect %s: %w", src.URL, err)
+	}
+	return lock.Entry{
+		URL:         src.URL,
+		Dest:        lockDest(path, src.Dest),
+		ResolvedURL: lockURL(path, src.URL, artifact.ResolvedURL),
+		Commit:      artifact.Commit,
+		ETag:        artifact.ETag,
+		Size:        artifact.Size,
+		Hash:        artifact.Hash,
+	}, nil
+}
+
+// writeLockfile records the fetched sources in the lockfile of the
+// configuration file, using the entries computed from their staged
+// downloads keyed by destination
+func writeLockfile(logger *slog.Logger, path string, cfg *config.FileConfig, fetched map[string]lock.Entry) error {
+	entries := make([]lock.Entry, 0, len(cfg.Sources))
+	for _, src := range cfg.Sources {
+		entry, ok := fetched[src.Dest]
+		if !ok {
+			return fmt.Errorf("no lock entry for %s: source was not fetched", src.URL)
+		}
+		entries = append(entries, entry)
+	}
+
+	lockPath := lock.PathFor(path)
+	lf := &lock.Lockfile{
+		Version: lock.FormatVersion,
+		Name:    cfg.Name,
+		Sources: entries,
+	}
+	if err := lf.Save(lockPath); err != nil {
+		return err
+	}
+	logger.Info("wrote lockfile", "path", lockPath)
+
+	return nil
+}
+
+// processSources processes all sources with the specified parallelism and
+// fills in the report of every source. Hook commands run in dir.
+func (p *Processor) processSources(ctx context.Context, logger *slog.Logger, f *fetcher.Fetcher, configName, dir string, sources []config.Source, parallelism int, reports []SourceReport) error {
 	semaphore := make(chan struct{}, parallelism)
+	queue := p.sched.register()
+	defer p.sched.unregister(queue)
+
+	// Only one source at a time collects the slots of its weight so
+	// heavy sources cannot deadlock each other holding partial slots
+	var acquireMu sync.Mutex
 	var wg sync.WaitGroup
 	errors := make([]error, len(sources))
 
@@ -130,16 +514,72 @@ func (p *Processor) processSources(ctx context.Context, f *fetcher.Fetcher, sour
 			defer wg.Done()
 
 			// Acquire semaphore
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
+			weight := sourceWeight(src, parallelism)
+			acquireMu.Lock()
+			for n := 0; n < weight; n++ {
+				semaphore <- struct{}{}
+			}
+			acquireMu.Unlock()
+			defer func() {
+				for n := 0; n < weight; n++ {
+					<-semaphore
+				}
+			}()
+
+			// Wait for a slot shared with the other configuration files
+			if err := p.sched.acquire(ctx, queue); err != nil {
+				errors[idx] = err
+				reports[idx] = SourceReport{Name: src.Name, URL: src.URL, Dest: src.Dest, Status: StatusFailed, Error: err.Error(), Err: err}
+				p.afterFetch(ctx, configName, reports[idx])
+				return
+			}
+			defer p.sched.release()
+
+			logger := logger.With("source", fmt.Sprintf("%d/%d", idx+1, len(sources)), "url", src.URL)
+			if src.Name != "" {
+				logger = logger.With("name", src.Name)
+			}
+
+			before := func() error {
+				if p.hooks.BeforeFetch != nil {
+					if err := p.hooks.BeforeFetch(ctx, configName, src); err != nil {
+						return err
+					}
+				}
+				return runCommands(ctx, logger, dir, src, src.Hooks.Before)
+			}
+			if err := before(); err != nil {
+				errors[idx] = err
+				reports[idx] = SourceReport{Name: src.Name, URL: src.URL, Dest: src.Dest, Status: StatusFailed, Error: err.Error(), Err: err}
+				p.afterFetch(ctx, configName, reports[idx])
+				logger.Error("source skipped", "error", err)
+				return
+			}
 
-			fmt.Printf("  [%d/%d] Fetching %s -> %s\n", idx+1, len(sources), src.URL, src.Dest)
-			err := f.FetchSource(ctx, src)
+			logger.Info("fetching source", "dest", src.Dest)
+			result, err := f.Fetch(ctx, src)
+			if err == nil {
+				err = runCommands(ctx, logger, dir, src, src.Hooks.After)
+			}
+			reports[idx] = SourceReport{
+				Name:       src.Name,
+				URL:        src.URL,
+				Dest:       src.Dest,
+				Status:     status(err),
+				Error:      errorText(err),
+				Attempts:   result.Attempts,
+				DurationMS: result.Duration.Milliseconds(),
+				Bytes:      result.Bytes,
+				Cached:     result.Cached,
+				Err:        err,
+			}
+			p.afterFetch(ctx, configName, reports[idx])
 			if err != nil {
 				errors[idx] = err
-				fmt.Printf("  [%d/%d] Failed: %v\n", idx+1, len(sources), err)
+				logger.Error("failed to fetch source", "attempts", result.Attempts, "error", err)
 			} else {
-				fmt.Printf("  [%d/%d] Success: %s\n", idx+1, len(sources), src.Dest)
+				logger.Info("fetched source", "dest", src.Dest, "attempts", result.Attempts,
+					"bytes", result.Bytes, "duration", result.Duration.Round(time.Millisecond), "cached", result.Cached)
 			}
 		}(i, source)
 	}
@@ -162,3 +602,22 @@ func (p *Processor) processSources(ctx context.Context, f *fetcher.Fetcher, sour
 
 	return nil
 }
+
+// afterFetch calls the AfterFetch hook if one is set
+func (p *Processor) afterFetch(ctx context.Context, configName string, report SourceReport) {
+	if p.hooks.AfterFetch != nil {
+		p.hooks.AfterFetch(ctx, configName, report)
+	}
+}
+
+// sourceWeight returns the number of parallel slots a source occupies,
+// which is at least one and at most the parallelism of its config
+func sourceWeight(src config.Source, parallelism int) int {
+	switch {
+	case src.Weight < 1:
+		return 1
+	case src.Weight > parallelism:
+		return parallelism
+	}
+	return src.Weight
+}
@@ -1,9 +1,21 @@
 package processor
 
 import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
+
+	"github.com/universal-development/go-getter-file/internal/config"
+	"github.com/universal-development/go-getter-file/internal/fetcher"
+	"github.com/universal-development/go-getter-file/internal/lock"
 )
 
 func TestExpandPath(t *testing.T) {
@@ -14,7 +26,11 @@ func TestExpandPath(t *testing.T) {
 	testFiles := []string{
 		"test1.go.getter.yaml",
 		"test2.go.getter.yaml",
+		"test3.go.getter.json",
+		"test4.go.getter.toml",
+		"test5.go.getter.hcl",
 		"other.yaml",
+		"other.json",
 	}
 
 	for _, file := range testFiles {
@@ -39,7 +55,7 @@ func TestExpandPath(t *testing.T) {
 		{
 			name:      "directory with multiple matching files",
 			path:      tmpDir,
-			wantCount: 2, // test1 and test2, not other.yaml
+			wantCount: 5, // every format, not other.yaml and other.json
 			wantError: false,
 		},
 		{
@@ -206,3 +222,341 @@ func TestNewWithNestedDirectories(t *testing.T) {
 		t.Errorf("New() with both directories found %d files, want 2", len(proc2.configFiles))
 	}
 }
+
+func TestProcessLock(t *testing.T) {
+	content := "hello"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, content)
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "project.go.getter.yaml")
+	configData := fmt.Sprintf(`version: 1
+name: "project"
+config:
+  retries: 0
+sources:
+  - url: "%s/hello.txt"
+    dest: "%s"
+`, server.URL, filepath.Join(tmpDir, "out"))
+	if err := os.WriteFile(configFile, []byte(configData), 0644); err != nil {
+		t.Fatalf("Failed to create config file: %v", err)
+	}
+
+	// Locked mode requires an existing lockfile
+	proc, err := New([]string{configFile}, WithLocked())
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if _, err := proc.Process(context.Background()); err == nil {
+		t.Fatal("Process() expected error without lockfile, got nil")
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "out")); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing to be fetched without lockfile, got %v", err)
+	}
+
+	proc, err = New([]string{configFile}, WithWriteLock())
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if _, err := proc.Process(context.Background()); err != nil {
+		t.Fatalf("Process() unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "project.go.getter.lock")); err != nil {
+		t.Fatalf("Expected lockfile to be written: %v", err)
+	}
+
+	proc, err = New([]string{configFile}, WithLocked())
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if _, err := proc.Process(context.Background()); err != nil {
+		t.Fatalf("Process() with unchanged sources unexpected error: %v", err)
+	}
+
+	content = "changed"
+	if _, err := proc.Process(context.Background()); err == nil {
+		t.Fatal("Process() expected error for changed source, got nil")
+	}
+
+	// The changed download is rejected before it replaces the destination
+	data, err := os.ReadFile(filepath.Join(tmpDir, "out", "hello.txt"))
+	if err != nil || string(data) != "hello" {
+		t.Errorf("dest content = %q (%v), want the locked content to be kept", data, err)
+	}
+}
+
+// Lock entries describe the download, so files in dest that the download
+// did not produce do not make the locked run fail
+func TestProcessLockDestWithOtherFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	src := filepath.Join(tmpDir, "src")
+	if err := os.MkdirAll(src, 0755); err != nil {
+		t.Fatalf("Failed to create source: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "lib.txt"), []byte("lib"), 0644); err != nil {
+		t.Fatalf("Failed to create source file: %v", err)
+	}
+	vendor := filepath.Join(tmpDir, "vendor")
+	if err := os.MkdirAll(vendor, 0755); err != nil {
+		t.Fatalf("Failed to create dest: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(vendor, "notes.txt"), []byte("mine"), 0644); err != nil {
+		t.Fatalf("Failed to create file in dest: %v", err)
+	}
+
+	configFile := filepath.Join(tmpDir, "project.go.getter.yaml")
+	configData := fmt.Sprintf(`version: 1
+name: "project"
+config:
+  retries: 0
+  output-root: %q
+sources:
+  - url: %q
+    dest: "vendor"
+`, tmpDir, src)
+	if err := os.WriteFile(configFile, []byte(configData), 0644); err != nil {
+		t.Fatalf("Failed to create config file: %v", err)
+	}
+
+	for _, opt := range []Option{WithWriteLock(), WithLocked()} {
+		proc, err := New([]string{configFile}, opt)
+		if err != nil {
+			t.Fatalf("New() unexpected error: %v", err)
+		}
+		if _, err := proc.Process(context.Background()); err != nil {
+			t.Fatalf("Process() unexpected error: %v", err)
+		}
+	}
+
+	if data, err := os.ReadFile(filepath.Join(vendor, "notes.txt")); err != nil || string(data) != "mine" {
+		t.Errorf("notes.txt = %q (%v), want it kept", data, err)
+	}
+}
+
+// Relative local sources resolve next to the configuration file and are
+// locked relative to it
+func TestProcessLockRelativeSource(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tmpDir, "src"), 0755); err != nil {
+		t.Fatalf("Failed to create source: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "src", "lib.txt"), []byte("lib"), 0644); err != nil {
+		t.Fatalf("Failed to create source file: %v", err)
+	}
+
+	configFile := filepath.Join(tmpDir, "project.go.getter.yaml")
+	configData := fmt.Sprintf(`version: 1
+name: "project"
+config:
+  retries: 0
+  output-root: %q
+sources:
+  - url: "./src"
+    dest: "vendor"
+`, tmpDir)
+	if err := os.WriteFile(configFile, []byte(configData), 0644); err != nil {
+		t.Fatalf("Failed to create config file: %v", err)
+	}
+
+	// Run from elsewhere so the working directory does not resolve ./src
+	t.Chdir(t.TempDir())
+
+	for _, opt := range []Option{WithWriteLock(), WithLocked()} {
+		proc, err := New([]string{configFile}, opt)
+		if err != nil {
+			t.Fatalf("New() unexpected error: %v", err)
+		}
+		if _, err := proc.Process(context.Background()); err != nil {
+			t.Fatalf("Process() unexpected error: %v", err)
+		}
+	}
+
+	lf, err := lock.Load(lock.PathFor(configFile))
+	if err != nil {
+		t.Fatalf("lock.Load() unexpected error: %v", err)
+	}
+	if len(lf.Sources) != 1 || lf.Sources[0].ResolvedURL != "file::src" {
+		t.Errorf("lockfile sources = %+v, want resolved url file::src", lf.Sources)
+	}
+}
+
+func TestProcessReport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "hello")
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "project.go.getter.yaml")
+	configData := fmt.Sprintf(`version: 1
+name: "project"
+config:
+  retries: 1
+sources:
+  - url: "%[1]s/hello.txt"
+    dest: "hello"
+  - url: "%[1]s/missing.txt"
+    dest: "missing"
+`, server.URL)
+	if err := os.WriteFile(configFile, []byte(configData), 0644); err != nil {
+		t.Fatalf("Failed to create config file: %v", err)
+	}
+
+	proc, err := New([]string{configFile}, WithLogger(slog.New(slog.DiscardHandler)))
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	report, err := proc.Process(context.Background())
+	if err == nil {
+		t.Fatal("Process() expected error for missing source, got nil")
+	}
+	if report.Status != StatusFailed || len(report.Configs) != 1 {
+		t.Fatalf("report = %+v, want one failed config", report)
+	}
+
+	cfg := report.Configs[0]
+	if cfg.Name != "project" || cfg.Status != StatusFailed || len(cfg.Sources) != 2 {
+		t.Fatalf("config report = %+v, want failed config with 2 sources", cfg)
+	}
+
+	ok, failed := cfg.Sources[0], cfg.Sources[1]
+	if ok.Status != StatusSuccess || ok.Attempts != 1 || ok.Bytes != 5 || ok.Error != "" {
+		t.Errorf("source report = %+v, want success after 1 attempt with 5 bytes", ok)
+	}
+	// A 404 is permanent and must not be retried
+	if failed.Status != StatusFailed || failed.Attempts != 1 || failed.Error == "" {
+		t.Errorf("source report = %+v, want failure after 1 attempt with an error", failed)
+	}
+	if ok.Dest != filepath.Join(tmpDir, "hello") {
+		t.Errorf("source report dest = %q, want %q", ok.Dest, filepath.Join(tmpDir, "hello"))
+	}
+}
+
+func TestSourceWeight(t *testing.T) {
+	tests := []struct {
+		weight int
+		want   int
+	}{
+		{weight: 0, want: 1},
+		{weight: 2, want: 2},
+		{weight: 10, want: 4},
+	}
+
+	for _, tt := range tests {
+		if got := sourceWeight(config.Source{Weight: tt.weight}, 4); got != tt.want {
+			t.Errorf("sourceWeight(%d) = %d, want %d", tt.weight, got, tt.want)
+		}
+	}
+}
+
+func TestProcessFakeDownloader(t *testing.T) {
+	tmpDir := t.TempDir()
+	configData := `version: 1
+name: "github"
+sources:
+  - url: "https://raw.githubusercontent.com/hashicorp/go-getter/main/README.md"
+    dest: "output/README.md"
+  - url: "github.com/hashicorp/go-getter//docs"
+    dest: "docs"
+`
+	configPath := filepath.Join(tmpDir, "github.go.getter.yaml")
+	if err := os.WriteFile(configPath, []byte(configData), 0644); err != nil {
+		t.Fatalf("Failed to create config file: %v", err)
+	}
+
+	var mu sync.Mutex
+	var urls []string
+	fake := fetcher.DownloaderFunc(func(ctx context.Context, source config.Source) error {
+		mu.Lock()
+		urls = append(urls, source.URL)
+		mu.Unlock()
+		return os.WriteFile(source.Dest, []byte("fake"), 0644)
+	})
+
+	proc, err := New([]string{configPath},
+		WithLogger(slog.New(slog.DiscardHandler)),
+		WithFetcherOptions(fetcher.WithDownloader(fake)))
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if _, err := proc.Process(context.Background()); err != nil {
+		t.Fatalf("Process() unexpected error: %v", err)
+	}
+
+	if len(urls) != 2 {
+		t.Errorf("downloader called for %v, want both sources", urls)
+	}
+	for _, dest := range []string{"output/README.md", "docs"} {
+		content, err := os.ReadFile(filepath.Join(tmpDir, dest))
+		if err != nil || string(content) != "fake" {
+			t.Errorf("%s = %q, %v, want the fake content", dest, content, err)
+		}
+	}
+}
+
+func TestProcessCommandHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook commands use sh")
+	}
+
+	tmpDir := t.TempDir()
+	configData := `version: 2
+name: "hooks"
+config:
+  parallelism: 1
+hooks:
+  after: ['echo "$GO_GETTER_FILE_SOURCE_NAME" >> fetched.log']
+sources:
+  readme:
+    url: "https://example.com/README.md"
+    dest: "README.md"
+    hooks:
+      after: ['test "$(cat "$GO_GETTER_FILE_SOURCE_DEST")" = fake']
+  skipped:
+    url: "https://example.com/skipped.txt"
+    dest: "skipped.txt"
+    hooks:
+      before: ["exit 3"]
+`
+	configPath := filepath.Join(tmpDir, "hooks.go.getter.yaml")
+	if err := os.WriteFile(configPath, []byte(configData), 0644); err != nil {
+		t.Fatalf("Failed to create config file: %v", err)
+	}
+
+	fake := fetcher.DownloaderFunc(func(ctx context.Context, source config.Source) error {
+		return os.WriteFile(source.Dest, []byte("fake"), 0644)
+	})
+	proc, err := New([]string{configPath},
+		WithLogger(slog.New(slog.DiscardHandler)),
+		WithFetcherOptions(fetcher.WithDownloader(fake)))
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	report, err := proc.Process(context.Background())
+	if err == nil {
+		t.Fatal("Process() expected error for the failing before hook, got nil")
+	}
+
+	sources := report.Configs[0].Sources
+	if sources[0].Name != "readme" || sources[0].Status != StatusSuccess {
+		t.Errorf("readme report = %+v, want a successful fetch", sources[0])
+	}
+	if sources[1].Name != "skipped" || sources[1].Status != StatusFailed || !strings.Contains(sources[1].Error, `hook "exit 3" failed`) {
+		t.Errorf("skipped report = %+v, want it failed by its before hook", sources[1])
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "skipped.txt")); !os.IsNotExist(err) {
+		t.Errorf("skipped.txt was fetched despite the failing before hook: %v", err)
+	}
+
+	log, err := os.ReadFile(filepath.Join(tmpDir, "fetched.log"))
+	if err != nil || string(log) != "readme\n" {
+		t.Errorf("fetched.log = %q, %v, want the after hook run next to the config for readme only", log, err)
+	}
+}
@@ -0,0 +1,72 @@
+package processor
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// Run and fetch statuses used in reports
+const (
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+)
+
+// Report is the structured summary of a Process run
+type Report struct {
+	Status     string         `json:"status"`
+	Started    time.Time      `json:"started"`
+	DurationMS int64          `json:"duration-ms"`
+	Configs    []ConfigReport `json:"configs"`
+}
+
+// ConfigReport is the outcome of processing a single configuration file
+type ConfigReport struct {
+	Path       string         `json:"path"`
+	Name       string         `json:"name,omitempty"`
+	Status     string         `json:"status"`
+	Error      string         `json:"error,omitempty"`
+	DurationMS int64          `json:"duration-ms"`
+	Sources    []SourceReport `json:"sources"`
+	// Err is the error behind Error
+	Err error `json:"-"`
+}
+
+// SourceReport is the outcome of fetching a single source
+type SourceReport struct {
+	// Name is set for the named sources of version 2 files
+	Name       string `json:"name,omitempty"`
+	URL        string `json:"url"`
+	Dest       string `json:"dest"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	Attempts   int    `json:"attempts"`
+	DurationMS int64  `json:"duration-ms"`
+	Bytes      int64  `json:"bytes"`
+	Cached     bool   `json:"cached"`
+	// Err is the error behind Error
+	Err error `json:"-"`
+}
+
+// WriteReport writes the report as indented json
+func WriteReport(w io.Writer, report *Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// status returns the report status for err
+func status(err error) string {
+	if err != nil {
+		return StatusFailed
+	}
+	return StatusSuccess
+}
+
+// errorText returns the message of err or an empty string
+func errorText(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return ""
+}
@@ -0,0 +1,132 @@
+package processor
+
+import (
+	"context"
+	"sync"
+)
+
+// scheduler limits the number of fetches running at once across all
+// configuration files. Free slots are handed to the configs with waiting
+// fetches in round-robin order so a config with many sources cannot starve
+// the others.
+type scheduler struct {
+	mu      sync.Mutex
+	limit   int
+	running int
+	queues  []*schedQueue
+	next    int
+}
+
+// schedQueue holds the fetches of one configuration file that wait for a slot
+type schedQueue struct {
+	waiters []chan struct{}
+}
+
+// newScheduler returns a scheduler running at most limit fetches at once.
+// A limit below one means no limit.
+func newScheduler(limit int) *scheduler {
+	return &scheduler{limit: limit}
+}
+
+// register adds a queue for the fetches of a configuration file
+func (s *scheduler) register() *schedQueue {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q := &schedQueue{}
+	s.queues = append(s.queues, q)
+	return q
+}
+
+// unregister removes a queue once all fetches of its config are done
+func (s *scheduler) unregister(q *schedQueue) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, other := range s.queues {
+		if other == q {
+			s.queues = append(s.queues[:i], s.queues[i+1:]...)
+			if s.next > i {
+				s.next--
+			}
+			break
+		}
+	}
+}
+
+// acquire blocks until a slot is available for a fetch from q or ctx is done
+func (s *scheduler) acquire(ctx context.Context, q *schedQueue) error {
+	if s.limit < 1 {
+		return nil
+	}
+
+	s.mu.Lock()
+	if s.running < s.limit && !s.waiting() {
+		s.running++
+		s.mu.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	q.waiters = append(q.waiters, ready)
+	s.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for i, w := range q.waiters {
+			if w == ready {
+				q.waiters = append(q.waiters[:i], q.waiters[i+1:]...)
+				return ctx.Err()
+			}
+		}
+		// The slot was handed over while the context was cancelled
+		s.running--
+		s.dispatch()
+		return ctx.Err()
+	}
+}
+
+// release frees the slot of a finished fetch
+func (s *scheduler) release() {
+	if s.limit < 1 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running--
+	s.dispatch()
+}
+
+// dispatch hands free slots to waiting fetches, taking one from each queue
+// in turn. The caller must hold s.mu.
+func (s *scheduler) dispatch() {
+	for s.running < s.limit && s.waiting() {
+		for range s.queues {
+			if s.next >= len(s.queues) {
+				s.next = 0
+			}
+			q := s.queues[s.next]
+			s.next++
+			if len(q.waiters) > 0 {
+				close(q.waiters[0])
+				q.waiters = q.waiters[1:]
+				s.running++
+				break
+			}
+		}
+	}
+}
+
+// waiting reports whether any fetch waits for a slot. The caller must hold s.mu.
+func (s *scheduler) waiting() bool {
+	for _, q := range s.queues {
+		if len(q.waiters) > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitForWaiters blocks until q has n fetches waiting for a slot
+func waitForWaiters(t *testing.T, s *scheduler, q *schedQueue, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		s.mu.Lock()
+		got := len(q.waiters)
+		s.mu.Unlock()
+		if got == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d waiters", n)
+}
+
+func TestSchedulerRoundRobin(t *testing.T) {
+	s := newScheduler(1)
+	a, b := s.register(), s.register()
+	ctx := context.Background()
+
+	// Occupy the only slot so every following fetch has to queue
+	if err := s.acquire(ctx, a); err != nil {
+		t.Fatalf("acquire() unexpected error: %v", err)
+	}
+
+	var mu sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	enqueue := func(q *schedQueue, name string, waiting int) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.acquire(ctx, q); err != nil {
+				t.Errorf("acquire() unexpected error: %v", err)
+				return
+			}
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			s.release()
+		}()
+		waitForWaiters(t, s, q, waiting)
+	}
+
+	enqueue(a, "a1", 1)
+	enqueue(a, "a2", 2)
+	enqueue(a, "a3", 3)
+	enqueue(b, "b1", 1)
+
+	s.release()
+	wg.Wait()
+
+	want := []string{"a1", "b1", "a2", "a3"}
+	if fmt.Sprint(order) != fmt.Sprint(want) {
+		t.Errorf("grant order = %v, want %v", order, want)
+	}
+}
+
+func TestSchedulerCancel(t *testing.T) {
+	s := newScheduler(1)
+	q := s.register()
+
+	if err := s.acquire(context.Background(), q); err != nil {
+		t.Fatalf("acquire() unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.acquire(ctx, q); err == nil {
+		t.Fatal("acquire() expected error for cancelled context, got nil")
+	}
+
+	s.release()
+	if err := s.acquire(context.Background(), q); err != nil {
+		t.Fatalf("acquire() after cancel unexpected error: %v", err)
+	}
+}
+
+func TestProcessMaxConcurrency(t *testing.T) {
+	var mu sync.Mutex
+	var active, peak int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		active++
+		if active > peak {
+			peak = active
+		}
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		active--
+		mu.Unlock()
+		fmt.Fprint(w, "hello")
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	for _, name := range []string{"one", "two", "three"} {
+		configData := fmt.Sprintf(`version: 1
+name: "%[2]s"
+config:
+  parallelism: 4
+sources:
+  - url: "%[1]s/a.txt"
+    dest: "%[2]s/a"
+  - url: "%[1]s/b.txt"
+    dest: "%[2]s/b"
+  - url: "%[1]s/c.txt"
+    dest: "%[2]s/c"
+`, server.URL, name)
+		path := filepath.Join(tmpDir, name+".go.getter.yaml")
+		if err := os.WriteFile(path, []byte(configData), 0644); err != nil {
+			t.Fatalf("Failed to create config file: %v", err)
+		}
+	}
+
+	proc, err := New([]string{tmpDir}, WithMaxConcurrency(2), WithLogger(slog.New(slog.DiscardHandler)))
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if _, err := proc.Process(context.Background()); err != nil {
+		t.Fatalf("Process() unexpected error: %v", err)
+	}
+
+	if peak > 2 {
+		t.Errorf("peak concurrent fetches = %d, want at most 2", peak)
+	}
+}
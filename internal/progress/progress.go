@@ -0,0 +1,287 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultTTYInterval is how often bars are redrawn on a terminal
+	DefaultTTYInterval = 200 * time.Millisecond
+	// DefaultLogInterval is how often progress lines are logged otherwise
+	DefaultLogInterval = 10 * time.Second
+
+	barWidth  = 24
+	nameWidth = 40
+)
+
+// Reporter renders the progress of downloads. On a terminal every active
+// download gets a bar with bytes, rate and ETA that is redrawn in place;
+// otherwise a progress record per download is logged periodically. Reporter
+// implements the go-getter ProgressTracker interface, and is an io.Writer
+// so that log lines written through it do not tear the bars.
+type Reporter struct {
+	mu       sync.Mutex
+	out      io.Writer
+	tty      bool
+	interval time.Duration
+	logger   *slog.Logger
+	active   []*transfer
+	drawn    int
+
+	stop     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+// transfer is a single download being tracked
+type transfer struct {
+	name    string
+	total   int64
+	start   time.Time
+	current atomic.Int64
+}
+
+// Option configures a Reporter
+type Option func(*Reporter)
+
+// WithInterval sets how often progress is rendered
+func WithInterval(d time.Duration) Option {
+	return func(r *Reporter) {
+		r.interval = d
+	}
+}
+
+// WithLogger sets the logger progress records are logged to when not
+// drawing bars. By default they are written as text lines to out.
+func WithLogger(l *slog.Logger) Option {
+	return func(r *Reporter) {
+		r.logger = l
+	}
+}
+
+// New creates a Reporter writing to out. With tty set progress bars are
+// drawn using terminal escape sequences. Close must be called to stop
+// rendering.
+func New(out io.Writer, tty bool, opts ...Option) *Reporter {
+	r := &Reporter{
+		out:      out,
+		tty:      tty,
+		interval: DefaultLogInterval,
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	if tty {
+		r.interval = DefaultTTYInterval
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	go r.loop()
+	return r
+}
+
+// TrackProgress wraps stream so the bytes read from it are reported
+func (r *Reporter) TrackProgress(src string, currentSize, totalSize int64, stream io.ReadCloser) io.ReadCloser {
+	t := &transfer{name: src, total: totalSize, start: time.Now()}
+	t.current.Store(currentSize)
+
+	r.mu.Lock()
+	r.active = append(r.active, t)
+	r.mu.Unlock()
+
+	return &trackedReader{ReadCloser: stream, reporter: r, transfer: t}
+}
+
+// Write writes log output, keeping the bars below it on a terminal
+func (r *Reporter) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.tty {
+		return r.out.Write(p)
+	}
+
+	r.clear()
+	n, err := r.out.Write(p)
+	r.draw()
+	return n, err
+}
+
+// Close stops rendering and removes the bars from the terminal
+func (r *Reporter) Close() error {
+	r.stopOnce.Do(func() {
+		close(r.stop)
+		<-r.stopped
+	})
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.tty {
+		r.clear()
+	}
+	return nil
+}
+
+// loop renders progress until the reporter is closed
+func (r *Reporter) loop() {
+	defer close(r.stopped)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			r.render()
+		}
+	}
+}
+
+// render redraws the bars or logs a line per active download
+func (r *Reporter) render() {
+	r.mu.Lock()
+	if r.tty {
+		r.clear()
+		r.draw()
+		r.mu.Unlock()
+		return
+	}
+
+	now := time.Now()
+	names := make([]string, len(r.active))
+	statuses := make([]string, len(r.active))
+	for i, t := range r.active {
+		names[i], statuses[i] = t.name, t.status(now)
+	}
+	r.mu.Unlock()
+
+	// Log without holding the lock, the logger may write through r
+	for i, name := range names {
+		if r.logger != nil {
+			r.logger.Info("download progress", "url", name, "progress", statuses[i])
+			continue
+		}
+		fmt.Fprintf(r, "  Progress %s: %s\n", name, statuses[i])
+	}
+}
+
+// finish stops tracking a download
+func (r *Reporter) finish(t *transfer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, other := range r.active {
+		if other == t {
+			r.active = append(r.active[:i], r.active[i+1:]...)
+			break
+		}
+	}
+	if r.tty {
+		r.clear()
+		r.draw()
+	}
+}
+
+// clear erases the bars drawn last. The caller must hold r.mu.
+func (r *Reporter) clear() {
+	for ; r.drawn > 0; r.drawn-- {
+		fmt.Fprint(r.out, "\x1b[1A\x1b[2K")
+	}
+}
+
+// draw prints a bar per active download. The caller must hold r.mu.
+func (r *Reporter) draw() {
+	now := time.Now()
+	for _, t := range r.active {
+		fmt.Fprintf(r.out, "  %-*s %s %s\n", nameWidth, shorten(t.name, nameWidth), t.bar(), t.status(now))
+		r.drawn++
+	}
+}
+
+// bar renders the completed fraction of a download
+func (t *transfer) bar() string {
+	if t.total <= 0 {
+		return "[" + strings.Repeat("?", barWidth) + "]"
+	}
+
+	filled := int(t.current.Load() * barWidth / t.total)
+	filled = min(max(filled, 0), barWidth)
+	return "[" + strings.Repeat("=", filled) + strings.Repeat(" ", barWidth-filled) + "]"
+}
+
+// status describes the bytes, rate and remaining time of a download
+func (t *transfer) status(now time.Time) string {
+	current := t.current.Load()
+	elapsed := now.Sub(t.start)
+
+	var rate float64
+	if elapsed > 0 {
+		rate = float64(current) / elapsed.Seconds()
+	}
+
+	if t.total <= 0 {
+		return fmt.Sprintf("%s, %s/s", FormatBytes(current), FormatBytes(int64(rate)))
+	}
+
+	status := fmt.Sprintf("%s / %s (%d%%), %s/s", FormatBytes(current), FormatBytes(t.total),
+		current*100/t.total, FormatBytes(int64(rate)))
+	if rate > 0 && current < t.total {
+		eta := time.Duration(float64(t.total-current) / rate * float64(time.Second))
+		status += ", ETA " + eta.Round(time.Second).String()
+	}
+	return status
+}
+
+// trackedReader counts the bytes read from a download stream
+type trackedReader struct {
+	io.ReadCloser
+	reporter *Reporter
+	transfer *transfer
+	once     sync.Once
+}
+
+func (t *trackedReader) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	t.transfer.current.Add(int64(n))
+	return n, err
+}
+
+func (t *trackedReader) Close() error {
+	t.once.Do(func() {
+		t.reporter.finish(t.transfer)
+	})
+	return t.ReadCloser.Close()
+}
+
+// FormatBytes formats a byte count with binary units
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// shorten truncates s to width runes keeping its end, which holds the file
+// name of a URL
+func shorten(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	return "..." + string(runes[len(runes)-width+3:])
+}
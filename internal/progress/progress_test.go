@@ -0,0 +1,102 @@
+package progress
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent use
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{n: 0, want: "0 B"},
+		{n: 1023, want: "1023 B"},
+		{n: 1536, want: "1.5 KiB"},
+		{n: 2 * 1024 * 1024 * 1024, want: "2.0 GiB"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatBytes(tt.n); got != tt.want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestReporterLogLines(t *testing.T) {
+	var out syncBuffer
+	r := New(&out, false, WithInterval(10*time.Millisecond))
+
+	stream := r.TrackProgress("https://example.com/big.bin", 0, 2048, io.NopCloser(strings.NewReader(strings.Repeat("x", 1024))))
+	if _, err := io.ReadAll(stream); err != nil {
+		t.Fatalf("ReadAll() unexpected error: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(out.String(), "Progress") && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	stream.Close()
+	r.Close()
+
+	got := out.String()
+	if !strings.Contains(got, "Progress https://example.com/big.bin: 1.0 KiB / 2.0 KiB (50%)") {
+		t.Errorf("log output = %q, want a progress line at 50%%", got)
+	}
+	if strings.Contains(got, "\x1b[") {
+		t.Errorf("log output contains terminal escapes: %q", got)
+	}
+}
+
+func TestReporterBars(t *testing.T) {
+	var out syncBuffer
+	// A long interval keeps the ticker out of the way of the assertions
+	r := New(&out, true, WithInterval(time.Hour))
+
+	stream := r.TrackProgress("https://example.com/big.bin", 0, 100, io.NopCloser(strings.NewReader(strings.Repeat("x", 50))))
+	if _, err := io.ReadAll(stream); err != nil {
+		t.Fatalf("ReadAll() unexpected error: %v", err)
+	}
+
+	if _, err := io.WriteString(r, "log line\n"); err != nil {
+		t.Fatalf("Write() unexpected error: %v", err)
+	}
+
+	got := out.String()
+	if !strings.HasPrefix(got, "log line\n") {
+		t.Errorf("output = %q, want the log line before the bar", got)
+	}
+	if !strings.Contains(got, "[============            ] 50 B / 100 B (50%)") {
+		t.Errorf("output = %q, want a half filled bar", got)
+	}
+
+	stream.Close()
+	r.Close()
+
+	// Finishing the download erases its bar
+	if !strings.HasSuffix(out.String(), "\x1b[1A\x1b[2K") {
+		t.Errorf("output = %q, want the bar to be erased", out.String())
+	}
+}
@@ -0,0 +1,137 @@
+package gogetterfile_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/hashicorp/go-getter/v2"
+	"github.com/universal-development/go-getter-file/pkg/gogetterfile"
+)
+
+// The declarations below pin the public API at compile time. Most types of
+// the package are aliases of internal types, so renaming or retyping an
+// internal field breaks this file instead of silently breaking users.
+
+var (
+	_ func(string, ...gogetterfile.LoadOption) (*gogetterfile.Config, error)    = gogetterfile.LoadFile
+	_ func([]byte, ...gogetterfile.LoadOption) (*gogetterfile.Config, error)    = gogetterfile.Load
+	_ func(io.Reader, ...gogetterfile.LoadOption) (*gogetterfile.Config, error) = gogetterfile.LoadReader
+	_ func(string) (*gogetterfile.Policy, error)                                = gogetterfile.LoadPolicy
+	_ func(map[string]string) gogetterfile.LoadOption                           = gogetterfile.WithVars
+	_ func(string) gogetterfile.LoadOption                                      = gogetterfile.WithFormat
+
+	_ func(context.Context, []*gogetterfile.Config, ...gogetterfile.Option) (*gogetterfile.Result, error) = gogetterfile.Run
+	_ func(context.Context, []string, ...gogetterfile.Option) (*gogetterfile.Result, error)               = gogetterfile.RunFiles
+	_ func(*slog.Logger) gogetterfile.Option                                                              = gogetterfile.WithLogger
+	_ func(gogetterfile.Hooks) gogetterfile.Option                                                        = gogetterfile.WithHooks
+	_ func(gogetterfile.ProgressTracker) gogetterfile.Option                                              = gogetterfile.WithProgress
+	_ func(int) gogetterfile.Option                                                                       = gogetterfile.WithMaxConcurrency
+	_ func(string) gogetterfile.Option                                                                    = gogetterfile.WithBaseDir
+	_ func(*gogetterfile.Policy) gogetterfile.Option                                                      = gogetterfile.WithPolicy
+	_ func(string) gogetterfile.Option                                                                    = gogetterfile.WithOutputRoot
+	_ func() gogetterfile.Option                                                                          = gogetterfile.WithWarnOnDestConflicts
+	_ func(string, getter.Getter) gogetterfile.Option                                                     = gogetterfile.WithGetter
+	_ func(getter.Detector) gogetterfile.Option                                                           = gogetterfile.WithDetector
+	_ func(string, getter.Decompressor) gogetterfile.Option                                               = gogetterfile.WithDecompressor
+	_ getter.ProgressTracker                                                                              = gogetterfile.ProgressTracker(nil)
+
+	_ func(*gogetterfile.Config)                                             = (*gogetterfile.Config).SetDefaults
+	_ func(*gogetterfile.Config) error                                       = (*gogetterfile.Config).Validate
+	_ func(*gogetterfile.Config, string)                                     = (*gogetterfile.Config).ResolveDests
+	_ func(*gogetterfile.Policy, string) error                               = (*gogetterfile.Policy).Check
+	_ func(gogetterfile.Backoff, *gogetterfile.Backoff) gogetterfile.Backoff = gogetterfile.Backoff.Merge
+	_ func(*gogetterfile.Result) []gogetterfile.SourceResult                 = (*gogetterfile.Result).Failed
+
+	_ error = gogetterfile.ErrOutsideRoot
+	_ error = gogetterfile.ErrPolicyDenied
+	_ error = gogetterfile.ErrDestConflict
+	_ error = (*gogetterfile.LoadError)(nil)
+	_ error = (*gogetterfile.ValidationError)(nil)
+	_ error = (*gogetterfile.FieldError)(nil)
+	_ error = (*gogetterfile.StatusError)(nil)
+	_ error = (*gogetterfile.ChecksumMismatchError)(nil)
+)
+
+var _ = gogetterfile.Config{
+	Version: 2,
+	Name:    "",
+	Extends: "",
+	Include: []string{},
+	Vars:    map[string]string{},
+	Config: gogetterfile.Settings{
+		Parallelism:  0,
+		Retries:      0,
+		Timeout:      time.Duration(0),
+		GoGetterPath: "",
+		CacheDir:     "",
+		BaseDir:      "",
+		Backoff: gogetterfile.Backoff{
+			Initial:    (*time.Duration)(nil),
+			Max:        (*time.Duration)(nil),
+			Multiplier: (*float64)(nil),
+			Jitter:     (*float64)(nil),
+		},
+		OutputRoot: "",
+		Getters:    []string{},
+	},
+	Policy: &gogetterfile.Policy{
+		AllowedSchemes:  []string{},
+		AllowedHosts:    []string{},
+		DeniedHosts:     []string{},
+		AllowedPatterns: []string{},
+		DeniedPatterns:  []string{},
+	},
+	Sources: []gogetterfile.Source{{
+		Name:         "",
+		URL:          "",
+		Dest:         "",
+		Timeout:      time.Duration(0),
+		Retries:      (*int)(nil),
+		Backoff:      (*gogetterfile.Backoff)(nil),
+		GoGetterPath: (*string)(nil),
+		Mode:         "",
+		Weight:       0,
+		Recursive:    (*bool)(nil),
+		Checksum:     "",
+		Hooks: gogetterfile.CommandHooks{
+			Before: []string{},
+			After:  []string{},
+		},
+	}},
+}
+
+var _ = gogetterfile.Hooks{
+	BeforeSource: func(ctx context.Context, config string, source gogetterfile.Source) error { return nil },
+	AfterSource:  func(ctx context.Context, config string, result gogetterfile.SourceResult) {},
+}
+
+var _ = gogetterfile.Result{
+	Started:  time.Time{},
+	Duration: time.Duration(0),
+	Configs: []gogetterfile.ConfigResult{{
+		Path:     "",
+		Name:     "",
+		Duration: time.Duration(0),
+		Err:      error(nil),
+		Sources: []gogetterfile.SourceResult{{
+			Name:     "",
+			URL:      "",
+			Dest:     "",
+			Attempts: 0,
+			Duration: time.Duration(0),
+			Bytes:    int64(0),
+			Cached:   false,
+			Err:      error(nil),
+		}},
+	}},
+}
+
+var _ = gogetterfile.LoadError{Path: "", Invalid: false, Err: error(nil)}
+
+var _ = gogetterfile.ValidationError{Errors: []*gogetterfile.FieldError{{File: "", Line: 0, Column: 0, Err: error(nil)}}}
+
+var _ = gogetterfile.StatusError{URL: "", StatusCode: 0}
+
+var _ = gogetterfile.ChecksumMismatchError{Path: "", Expected: "", Actual: ""}
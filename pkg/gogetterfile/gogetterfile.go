@@ -0,0 +1,458 @@
+// Package gogetterfile fetches the sources described by go-getter-file
+// configurations from Go programs. It loads configurations from files,
+// bytes or readers, runs them with a logger, hooks and cancellation, and
+// returns a typed result for every source.
+//
+// Config, Settings, Source, Backoff, Policy, CommandHooks and the error
+// types are aliases of the types the go-getter-file command uses, so the
+// library and the command read configurations the same way. Their exported
+// fields and methods are part of the API of this package and follow its
+// compatibility promises, even though they are declared in internal
+// packages.
+package gogetterfile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/go-getter/v2"
+	"github.com/universal-development/go-getter-file/internal/checksum"
+	"github.com/universal-development/go-getter-file/internal/config"
+	"github.com/universal-development/go-getter-file/internal/fetcher"
+	"github.com/universal-development/go-getter-file/internal/processor"
+)
+
+// Config is a parsed and validated configuration
+type Config = config.FileConfig
+
+// Settings are the global settings of a configuration
+type Settings = config.Config
+
+// Source is a single source of a configuration
+type Source = config.Source
+
+// CommandHooks are the shell commands a version 2 configuration runs
+// around the fetch of a source. Configurations passed to Run run them in
+// the working directory.
+type CommandHooks = config.CommandHooks
+
+// Backoff configures the wait between retries
+type Backoff = config.Backoff
+
+// Policy restricts the schemes, hosts and URLs sources may be fetched from
+type Policy = config.Policy
+
+// ErrOutsideRoot is wrapped by the errors of destinations outside the
+// output root
+var ErrOutsideRoot = config.ErrOutsideRoot
+
+// ErrPolicyDenied is wrapped by the errors of sources a policy does not allow
+var ErrPolicyDenied = config.ErrPolicyDenied
+
+// ErrDestConflict is wrapped by the errors of runs stopped because sources
+// have the same or nested destinations
+var ErrDestConflict = processor.ErrDestConflict
+
+// LoadError is returned when a configuration cannot be parsed or is invalid
+type LoadError = config.LoadError
+
+// ValidationError lists every problem found in a configuration. It is
+// wrapped by a LoadError and its problems carry file:line:column positions.
+type ValidationError = config.ValidationError
+
+// FieldError is a problem with a single value of a configuration
+type FieldError = config.FieldError
+
+// StatusError is returned for HTTP responses with an unsuccessful status
+// while crawling the directory listings of recursive sources
+type StatusError = fetcher.StatusError
+
+// ChecksumMismatchError is returned when a download does not match its checksum
+type ChecksumMismatchError = checksum.MismatchError
+
+// LoadOption configures how a configuration is loaded
+type LoadOption func(*loadOptions)
+
+type loadOptions struct {
+	vars   map[string]string
+	format string
+}
+
+// WithVars sets variables that take precedence over the vars section of the
+// configuration
+func WithVars(vars map[string]string) LoadOption {
+	return func(o *loadOptions) {
+		o.vars = vars
+	}
+}
+
+// WithFormat parses the configuration in the named format: "yaml", "json",
+// "toml" or "hcl". Files are otherwise parsed in the format of their
+// extension, and data passed to Load as YAML.
+func WithFormat(name string) LoadOption {
+	return func(o *loadOptions) {
+		o.format = name
+	}
+}
+
+func (o loadOptions) configOptions() []config.LoadOption {
+	opts := []config.LoadOption{config.WithVars(o.vars)}
+	if o.format != "" {
+		opts = append(opts, config.WithFormat(o.format))
+	}
+	return opts
+}
+
+func newLoadOptions(opts []LoadOption) loadOptions {
+	var o loadOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// LoadFile loads a configuration file. Relative destinations are resolved
+// against the directory of the file, or its base-dir.
+func LoadFile(path string, opts ...LoadOption) (*Config, error) {
+	cfg, err := config.LoadConfig(path, newLoadOptions(opts).configOptions()...)
+	if err != nil {
+		return nil, err
+	}
+	cfg.ResolveDests(dirOf(path))
+	return cfg, nil
+}
+
+// dirOf returns the absolute directory of path so resolved destinations do
+// not depend on the working directory of a later run
+func dirOf(path string) string {
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return filepath.Dir(path)
+	}
+	return dir
+}
+
+// LoadPolicy loads a policy file
+func LoadPolicy(path string) (*Policy, error) {
+	return config.LoadPolicy(path)
+}
+
+// Load parses a configuration from YAML data, or data in the format set
+// with WithFormat. Relative destinations are resolved when the
+// configuration is run.
+func Load(data []byte, opts ...LoadOption) (*Config, error) {
+	return config.Parse(data, newLoadOptions(opts).configOptions()...)
+}
+
+// LoadReader parses a configuration from a reader of YAML data, or data in
+// the format set with WithFormat
+func LoadReader(r io.Reader, opts ...LoadOption) (*Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	return Load(data, opts...)
+}
+
+// Hooks are called around every fetch. They are called concurrently for
+// sources that are fetched in parallel.
+type Hooks struct {
+	// BeforeSource is called before a source is fetched. Returning an error
+	// fails the source without fetching it.
+	BeforeSource func(ctx context.Context, config string, source Source) error
+	// AfterSource is called with the result of every source
+	AfterSource func(ctx context.Context, config string, result SourceResult)
+}
+
+// ProgressTracker receives the download streams to report their progress.
+// It matches the go-getter ProgressTracker interface.
+type ProgressTracker interface {
+	TrackProgress(src string, currentSize, totalSize int64, stream io.ReadCloser) io.ReadCloser
+}
+
+// Option configures a run
+type Option func(*runOptions)
+
+type runOptions struct {
+	logger     *slog.Logger
+	hooks      Hooks
+	progress   ProgressTracker
+	maxFetches int
+	baseDir    string
+	policy     *Policy
+	outputRoot string
+	warnDests  bool
+	fetcher    []fetcher.Option
+}
+
+// WithLogger sets the logger progress messages are logged to. By default
+// nothing is logged.
+func WithLogger(l *slog.Logger) Option {
+	return func(o *runOptions) {
+		o.logger = l
+	}
+}
+
+// WithHooks sets the hooks called around every fetch
+func WithHooks(h Hooks) Option {
+	return func(o *runOptions) {
+		o.hooks = h
+	}
+}
+
+// WithProgress reports the progress of every download to tracker
+func WithProgress(tracker ProgressTracker) Option {
+	return func(o *runOptions) {
+		o.progress = tracker
+	}
+}
+
+// WithMaxConcurrency limits the number of fetches running at once across
+// all configurations
+func WithMaxConcurrency(n int) Option {
+	return func(o *runOptions) {
+		o.maxFetches = n
+	}
+}
+
+// WithBaseDir resolves relative destinations of configurations loaded with
+// Load or LoadReader against dir instead of the working directory
+func WithBaseDir(dir string) Option {
+	return func(o *runOptions) {
+		o.baseDir = dir
+	}
+}
+
+// WithPolicy checks the sources of every configuration against policy, in
+// addition to the policy section of each configuration
+func WithPolicy(policy *Policy) Option {
+	return func(o *runOptions) {
+		o.policy = policy
+	}
+}
+
+// WithOutputRoot rejects destinations outside dir and downloads containing
+// symlinks or special files that escape their destination, overriding the
+// output-root of every configuration
+func WithOutputRoot(dir string) Option {
+	return func(o *runOptions) {
+		o.outputRoot = dir
+	}
+}
+
+// WithWarnOnDestConflicts fetches sources with the same or nested
+// destinations after logging a warning. By default such a run fails with
+// ErrDestConflict before anything is fetched.
+func WithWarnOnDestConflicts() Option {
+	return func(o *runOptions) {
+		o.warnDests = true
+	}
+}
+
+// WithGetter registers a go-getter getter under name, e.g. s3 for s3::
+// URLs. Custom getters are asked before the built-in ones and replace the
+// built-in getter of the same name. The name is also the one listed in the
+// getters allowlist of a configuration.
+func WithGetter(name string, g getter.Getter) Option {
+	return func(o *runOptions) {
+		o.fetcher = append(o.fetcher, fetcher.WithGetter(name, g))
+	}
+}
+
+// WithDetector registers a detector that rewrites source URLs before a
+// getter is chosen
+func WithDetector(d getter.Detector) Option {
+	return func(o *runOptions) {
+		o.fetcher = append(o.fetcher, fetcher.WithDetector(d))
+	}
+}
+
+// WithDecompressor registers a decompressor for archives with the given
+// extension
+func WithDecompressor(ext string, d getter.Decompressor) Option {
+	return func(o *runOptions) {
+		o.fetcher = append(o.fetcher, fetcher.WithDecompressor(ext, d))
+	}
+}
+
+// Result is the outcome of a run
+type Result struct {
+	Started  time.Time
+	Duration time.Duration
+	Configs  []ConfigResult
+}
+
+// ConfigResult is the outcome of running a single configuration
+type ConfigResult struct {
+	// Path is the configuration file, or the configuration name for
+	// configurations that were not loaded from a file
+	Path     string
+	Name     string
+	Duration time.Duration
+	Sources  []SourceResult
+	Err      error
+}
+
+// SourceResult is the outcome of fetching a single source
+type SourceResult struct {
+	// Name is set for the named sources of version 2 configurations
+	Name     string
+	URL      string
+	Dest     string
+	Attempts int
+	Duration time.Duration
+	Bytes    int64
+	Cached   bool
+	Err      error
+}
+
+// Failed returns the results of the sources that failed
+func (r *Result) Failed() []SourceResult {
+	var failed []SourceResult
+	for _, cfg := range r.Configs {
+		for _, src := range cfg.Sources {
+			if src.Err != nil {
+				failed = append(failed, src)
+			}
+		}
+	}
+	return failed
+}
+
+// Run fetches the sources of the given configurations. Configurations
+// built in code get the defaults of loaded ones for unset values, e.g. the
+// timeout, and are validated first; an invalid configuration fails with a
+// *LoadError before anything is fetched. The result holds the outcome of
+// every source and is returned on failure as well; the error joins the
+// errors of the configurations that failed.
+func Run(ctx context.Context, configs []*Config, opts ...Option) (*Result, error) {
+	o := newRunOptions(opts)
+
+	seen := make(map[string]bool)
+	var procOpts []processor.Option
+	for i, cfg := range configs {
+		if cfg == nil {
+			return nil, fmt.Errorf("config %d is nil", i)
+		}
+		name := cfg.Name
+		if seen[name] {
+			name = fmt.Sprintf("%s#%d", cfg.Name, i)
+		}
+		seen[name] = true
+
+		resolved := *cfg
+		resolved.Sources = append([]Source(nil), cfg.Sources...)
+		resolved.SetDefaults()
+		if err := resolved.Validate(); err != nil {
+			return nil, fmt.Errorf("config %d: %w", i, &LoadError{Invalid: true, Err: err})
+		}
+		resolved.ResolveDests(o.baseDir)
+		procOpts = append(procOpts, processor.WithConfig(name, &resolved))
+	}
+
+	return run(ctx, nil, o, procOpts)
+}
+
+// RunFiles loads and fetches configuration files or directories of
+// *.go.getter.yaml, .json, .toml and .hcl files. Relative destinations are
+// resolved against the directory of each file.
+func RunFiles(ctx context.Context, paths []string, opts ...Option) (*Result, error) {
+	return run(ctx, paths, newRunOptions(opts), nil)
+}
+
+func newRunOptions(opts []Option) runOptions {
+	o := runOptions{logger: slog.New(slog.DiscardHandler)}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// run runs the processor and converts its report
+func run(ctx context.Context, paths []string, o runOptions, procOpts []processor.Option) (*Result, error) {
+	procOpts = append(procOpts,
+		processor.WithLogger(o.logger),
+		processor.WithMaxConcurrency(o.maxFetches),
+		processor.WithPolicy(o.policy),
+		processor.WithFetcherOptions(o.fetcher...),
+		processor.WithHooks(processor.Hooks{
+			BeforeFetch: o.hooks.BeforeSource,
+			AfterFetch:  afterFetch(o.hooks.AfterSource),
+		}),
+	)
+	if o.progress != nil {
+		procOpts = append(procOpts, processor.WithProgress(o.progress))
+	}
+	if o.warnDests {
+		procOpts = append(procOpts, processor.WithDestConflicts(processor.DestConflictsWarn))
+	}
+	if o.outputRoot != "" {
+		procOpts = append(procOpts, processor.WithOverrides(config.Overrides{OutputRoot: &o.outputRoot}))
+	}
+
+	proc, err := processor.New(paths, procOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	report, _ := proc.Process(ctx)
+	result := newResult(report)
+
+	var errs []error
+	for _, cfg := range result.Configs {
+		if cfg.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", cfg.Path, cfg.Err))
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+// afterFetch adapts an AfterSource hook to the processor
+func afterFetch(hook func(context.Context, string, SourceResult)) func(context.Context, string, processor.SourceReport) {
+	if hook == nil {
+		return nil
+	}
+	return func(ctx context.Context, configName string, report processor.SourceReport) {
+		hook(ctx, configName, newSourceResult(report))
+	}
+}
+
+func newResult(report *processor.Report) *Result {
+	result := &Result{
+		Started:  report.Started,
+		Duration: time.Duration(report.DurationMS) * time.Millisecond,
+		Configs:  make([]ConfigResult, 0, len(report.Configs)),
+	}
+	for _, cfg := range report.Configs {
+		cr := ConfigResult{
+			Path:     cfg.Path,
+			Name:     cfg.Name,
+			Duration: time.Duration(cfg.DurationMS) * time.Millisecond,
+			Sources:  make([]SourceResult, 0, len(cfg.Sources)),
+			Err:      cfg.Err,
+		}
+		for _, src := range cfg.Sources {
+			cr.Sources = append(cr.Sources, newSourceResult(src))
+		}
+		result.Configs = append(result.Configs, cr)
+	}
+	return result
+}
+
+func newSourceResult(report processor.SourceReport) SourceResult {
+	return SourceResult{
+		Name:     report.Name,
+		URL:      report.URL,
+		Dest:     report.Dest,
+		Attempts: report.Attempts,
+		Duration: time.Duration(report.DurationMS) * time.Millisecond,
+		Bytes:    report.Bytes,
+		Cached:   report.Cached,
+		Err:      report.Err,
+	}
+}
@@ -0,0 +1,234 @@
+package gogetterfile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	data := []byte(`version: 1
+name: "test"
+vars:
+  host: "example.com"
+sources:
+  - url: "https://${host}/file.txt"
+    dest: "out/file.txt"
+`)
+
+	cfg, err := Load(data, WithVars(map[string]string{"host": "example.org"}))
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if cfg.Sources[0].URL != "https://example.org/file.txt" {
+		t.Errorf("Sources[0].URL = %q, want the overridden variable", cfg.Sources[0].URL)
+	}
+	if cfg.Config.Retries != 3 {
+		t.Errorf("Config.Retries = %d, want default 3", cfg.Config.Retries)
+	}
+
+	if _, err := LoadReader(strings.NewReader(string(data))); err != nil {
+		t.Fatalf("LoadReader() unexpected error: %v", err)
+	}
+
+	hcl := "version = 1\nname = \"test\"\nsource {\n  url  = \"https://example.com/file.txt\"\n  dest = \"out/file.txt\"\n}\n"
+	cfg, err = Load([]byte(hcl), WithFormat("hcl"))
+	if err != nil {
+		t.Fatalf("Load() with format hcl unexpected error: %v", err)
+	}
+	if len(cfg.Sources) != 1 || cfg.Sources[0].Dest != "out/file.txt" {
+		t.Errorf("Sources = %+v, want the HCL source", cfg.Sources)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		invalid bool
+	}{
+		{name: "malformed yaml", data: "version: [1", invalid: false},
+		{name: "invalid config", data: "version: 2\nsources: []\n", invalid: true},
+		{name: "unknown field", data: "version: 1\nname: \"test\"\nsource: []\n", invalid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load([]byte(tt.data))
+			var loadErr *LoadError
+			if !errors.As(err, &loadErr) {
+				t.Fatalf("Load() error = %v, want a *LoadError", err)
+			}
+			if loadErr.Invalid != tt.invalid {
+				t.Errorf("LoadError.Invalid = %v, want %v", loadErr.Invalid, tt.invalid)
+			}
+			var validationErr *ValidationError
+			if errors.As(err, &validationErr) != tt.invalid {
+				t.Errorf("Load() error = %v, want a *ValidationError only for invalid configs", err)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "hello")
+	}))
+	defer server.Close()
+
+	cfg, err := Load([]byte(fmt.Sprintf(`version: 1
+name: "test"
+config:
+  retries: 0
+sources:
+  - url: "%[1]s/file.txt"
+    dest: "out/file.txt"
+  - url: "%[1]s/missing.txt"
+    dest: "out/missing.txt"
+`, server.URL)))
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+
+	baseDir := t.TempDir()
+	var mu sync.Mutex
+	var before, after []string
+	hooks := Hooks{
+		BeforeSource: func(ctx context.Context, config string, source Source) error {
+			mu.Lock()
+			defer mu.Unlock()
+			before = append(before, config+" "+source.URL)
+			return nil
+		},
+		AfterSource: func(ctx context.Context, config string, result SourceResult) {
+			mu.Lock()
+			defer mu.Unlock()
+			after = append(after, config+" "+result.URL)
+		},
+	}
+
+	result, err := Run(context.Background(), []*Config{cfg}, WithBaseDir(baseDir), WithHooks(hooks))
+	if err == nil {
+		t.Fatal("Run() expected error for the missing source, got nil")
+	}
+	if len(before) != 2 || len(after) != 2 {
+		t.Errorf("hooks called %d and %d times, want 2 each", len(before), len(after))
+	}
+
+	if len(result.Configs) != 1 || len(result.Configs[0].Sources) != 2 {
+		t.Fatalf("result = %+v, want one config with two sources", result)
+	}
+	ok := result.Configs[0].Sources[0]
+	if ok.Err != nil || ok.Bytes != 5 || ok.Attempts != 1 {
+		t.Errorf("Sources[0] = %+v, want a successful fetch of 5 bytes", ok)
+	}
+	if ok.Dest != filepath.Join(baseDir, "out", "file.txt") {
+		t.Errorf("Sources[0].Dest = %q, want it resolved against the base dir", ok.Dest)
+	}
+	if _, err := os.Stat(ok.Dest); err != nil {
+		t.Errorf("downloaded file missing: %v", err)
+	}
+
+	failed := result.Failed()
+	if len(failed) != 1 {
+		t.Fatalf("Failed() = %+v, want one failed source", failed)
+	}
+	if !strings.Contains(failed[0].Err.Error(), "404") {
+		t.Errorf("failed source error = %v, want a 404", failed[0].Err)
+	}
+
+	// The configuration passed to Run is left untouched
+	if cfg.Sources[0].Dest != "out/file.txt" {
+		t.Errorf("Run() modified the config dest to %q", cfg.Sources[0].Dest)
+	}
+}
+
+func TestRunBeforeSourceError(t *testing.T) {
+	cfg, err := Load([]byte(`version: 1
+name: "test"
+sources:
+  - url: "https://example.com/file.txt"
+    dest: "file.txt"
+`))
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+
+	skip := errors.New("skipped")
+	hooks := Hooks{
+		BeforeSource: func(ctx context.Context, config string, source Source) error {
+			return skip
+		},
+	}
+	result, err := Run(context.Background(), []*Config{cfg}, WithBaseDir(t.TempDir()), WithHooks(hooks))
+	if err == nil {
+		t.Fatal("Run() expected error, got nil")
+	}
+	if failed := result.Failed(); len(failed) != 1 || !errors.Is(failed[0].Err, skip) {
+		t.Errorf("Failed() = %+v, want the hook error", failed)
+	}
+}
+
+func TestRunBuiltConfig(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "hello")
+	}))
+	defer server.Close()
+
+	// Unset values like the timeout get the defaults of loaded configs
+	cfg := &Config{
+		Version: 1,
+		Name:    "built",
+		Sources: []Source{{URL: server.URL + "/file.txt", Dest: "file.txt"}},
+	}
+	if _, err := Run(context.Background(), []*Config{cfg}, WithBaseDir(t.TempDir())); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	_, err := Run(context.Background(), []*Config{{Version: 1, Name: "empty"}})
+	var loadErr *LoadError
+	if !errors.As(err, &loadErr) || !loadErr.Invalid || !strings.Contains(err.Error(), "at least one source is required") {
+		t.Errorf("Run() error = %v, want an invalid config error", err)
+	}
+}
+
+func TestRunFiles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "hello")
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	configData := fmt.Sprintf(`version: 1
+name: "files"
+sources:
+  - url: "%s/file.txt"
+    dest: "out/file.txt"
+`, server.URL)
+	path := filepath.Join(tmpDir, "test.go.getter.yaml")
+	if err := os.WriteFile(path, []byte(configData), 0644); err != nil {
+		t.Fatalf("Failed to create config file: %v", err)
+	}
+
+	result, err := RunFiles(context.Background(), []string{tmpDir})
+	if err != nil {
+		t.Fatalf("RunFiles() unexpected error: %v", err)
+	}
+	if len(result.Failed()) != 0 {
+		t.Errorf("Failed() = %+v, want none", result.Failed())
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "out", "file.txt")); err != nil {
+		t.Errorf("downloaded file missing next to the config: %v", err)
+	}
+}
@@ -0,0 +1,13 @@
+# HCL configuration - sources are keyed by name in version 2
+version = 2
+name    = "hcl-format"
+
+config {
+  retries = 3
+  timeout = "30s"
+}
+
+source "readme" {
+  url  = "https://raw.githubusercontent.com/hashicorp/go-getter/main/README.md"
+  dest = "output/hcl/README.md"
+}
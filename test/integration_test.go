@@ -3,7 +3,11 @@ package test
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
 	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
@@ -61,6 +65,22 @@ func runCLI(t *testing.T, workDir string, args ...string) (string, error) {
 	return output, err
 }
 
+// copyFixture copies a fixture file into dir and returns the path of the copy
+func copyFixture(t *testing.T, dir, name string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(fixturesPath, name))
+	if err != nil {
+		t.Fatalf("Failed to read fixture %s: %v", name, err)
+	}
+
+	path := filepath.Join(dir, filepath.Base(name))
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to copy fixture %s: %v", name, err)
+	}
+	return path
+}
+
 func ensureNetwork(t *testing.T) {
 	t.Helper()
 
@@ -77,10 +97,10 @@ func TestFetchFromGitHub(t *testing.T) {
 	ensureNetwork(t)
 	testDir := t.TempDir()
 
-	// Use fixture file
-	fixtureFile := filepath.Join(fixturesPath, "github-single-file.go.getter.yaml")
+	// Copy fixture file so relative destinations resolve into the test directory
+	fixtureFile := copyFixture(t, testDir, "github-single-file.go.getter.yaml")
 
-	// Run the application from test directory (so relative paths in fixture work)
+	// Run the application from test directory
 	output, err := runCLI(t, testDir, fixtureFile)
 	if err != nil {
 		t.Fatalf("Command failed: %v\nOutput: %s", err, output)
@@ -114,8 +134,8 @@ func TestFetchFromGitHubDirectory(t *testing.T) {
 	ensureNetwork(t)
 	testDir := t.TempDir()
 
-	// Use fixture file
-	fixtureFile := filepath.Join(fixturesPath, "github-directory.go.getter.yaml")
+	// Copy fixture file so relative destinations resolve into the test directory
+	fixtureFile := copyFixture(t, testDir, "github-directory.go.getter.yaml")
 
 	// Run the application from test directory
 	output, err := runCLI(t, testDir, fixtureFile)
@@ -146,8 +166,8 @@ func TestProcessMultipleFiles(t *testing.T) {
 	ensureNetwork(t)
 	testDir := t.TempDir()
 
-	// Use fixture file
-	fixtureFile := filepath.Join(fixturesPath, "multiple-files.go.getter.yaml")
+	// Copy fixture file so relative destinations resolve into the test directory
+	fixtureFile := copyFixture(t, testDir, "multiple-files.go.getter.yaml")
 
 	// Run the application from test directory
 	output, err := runCLI(t, testDir, fixtureFile)
@@ -184,9 +204,9 @@ func TestProcessMultipleConfigFiles(t *testing.T) {
 	ensureNetwork(t)
 	testDir := t.TempDir()
 
-	// Use fixtures directly
-	config1 := filepath.Join(fixturesPath, "config1.go.getter.yaml")
-	config2 := filepath.Join(fixturesPath, "config2.go.getter.yaml")
+	// Copy fixtures so relative destinations resolve into the test directory
+	config1 := copyFixture(t, testDir, "config1.go.getter.yaml")
+	config2 := copyFixture(t, testDir, "config2.go.getter.yaml")
 
 	// Run with both config files from test directory
 	output, err := runCLI(t, testDir, config1, config2)
@@ -222,8 +242,14 @@ func TestProcessDirectory(t *testing.T) {
 	ensureNetwork(t)
 	testDir := t.TempDir()
 
-	// Use fixtures batch-configs directory
-	configsDir := filepath.Join(fixturesPath, "batch-configs")
+	// Copy the batch-configs fixtures; destinations resolve next to the configs
+	configsDir := filepath.Join(testDir, "batch-configs")
+	if err := os.Mkdir(configsDir, 0755); err != nil {
+		t.Fatalf("Failed to create configs directory: %v", err)
+	}
+	for _, name := range []string{"batch1", "batch2", "batch3"} {
+		copyFixture(t, configsDir, filepath.Join("batch-configs", name+".go.getter.yaml"))
+	}
 
 	// Run with directory from test directory
 	output, err := runCLI(t, testDir, configsDir)
@@ -243,7 +269,7 @@ func TestProcessDirectory(t *testing.T) {
 	}
 
 	for _, expected := range expectedFiles {
-		outputFile := filepath.Join(testDir, expected.dir, expected.file)
+		outputFile := filepath.Join(configsDir, expected.dir, expected.file)
 		if _, err := os.Stat(outputFile); os.IsNotExist(err) {
 			t.Errorf("Expected output file %s does not exist", outputFile)
 		}
@@ -333,3 +359,436 @@ func TestNonExistentFile(t *testing.T) {
 
 	t.Log("Non-existent file correctly handled")
 }
+
+// TestValidateCommand tests the validate subcommand
+func TestValidateCommand(t *testing.T) {
+	validConfig := filepath.Join(fixturesPath, "simple.go.getter.yaml")
+	invalidConfig := filepath.Join(fixturesPath, "invalid.go.getter.yaml")
+
+	output, err := runCLI(t, "", "validate", validConfig)
+	if err != nil {
+		t.Fatalf("validate failed for valid config: %v\nOutput: %s", err, output)
+	}
+	if !strings.Contains(output, "OK") {
+		t.Errorf("Expected OK in validate output, got: %s", output)
+	}
+
+	output, err = runCLI(t, "", "validate", validConfig, invalidConfig)
+	if err == nil {
+		t.Fatal("Expected validate to fail with invalid config, but it succeeded")
+	}
+	if !strings.Contains(output, "FAIL") {
+		t.Errorf("Expected FAIL in validate output, got: %s", output)
+	}
+
+	output, err = runCLI(t, "", "validate", "--output", "json", invalidConfig)
+	if err == nil {
+		t.Fatal("Expected validate to fail with invalid config, but it succeeded")
+	}
+	var results []struct {
+		Valid    bool `json:"valid"`
+		Problems []struct {
+			Line    int    `json:"line"`
+			Message string `json:"message"`
+		} `json:"problems"`
+	}
+	if err := json.Unmarshal([]byte(output), &results); err != nil {
+		t.Fatalf("Failed to parse validate output: %v\nOutput: %s", err, output)
+	}
+	if len(results) != 1 || results[0].Valid || len(results[0].Problems) != 2 {
+		t.Fatalf("Expected one invalid config with two problems, got: %s", output)
+	}
+	if problem := results[0].Problems[1]; problem.Line != 3 || problem.Message != "at least one source is required" {
+		t.Errorf("Expected the empty sources reported on line 3, got %+v", problem)
+	}
+}
+
+// TestSchemaCommand tests the JSON Schema printed by the schema subcommand
+func TestSchemaCommand(t *testing.T) {
+	output, err := runCLI(t, "", "schema")
+	if err != nil {
+		t.Fatalf("schema failed: %v\nOutput: %s", err, output)
+	}
+
+	var schema struct {
+		Schema string `json:"$schema"`
+		OneOf  []struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"oneOf"`
+	}
+	if err := json.Unmarshal([]byte(output), &schema); err != nil {
+		t.Fatalf("Failed to parse schema output: %v\nOutput: %s", err, output)
+	}
+	if schema.Schema == "" || len(schema.OneOf) != 2 {
+		t.Fatalf("Expected a JSON Schema for both format versions, got: %s", output)
+	}
+	for i, version := range schema.OneOf {
+		if version.Properties["sources"] == nil {
+			t.Errorf("Expected a sources property in version %d, got: %s", i+1, output)
+		}
+	}
+
+	if _, err := runCLI(t, "", "schema", "extra"); err == nil {
+		t.Error("Expected schema to fail with arguments, but it succeeded")
+	}
+}
+
+// TestListCommandJSON tests the list subcommand with json output
+func TestListCommandJSON(t *testing.T) {
+	configFile := filepath.Join(fixturesPath, "multiple-files.go.getter.yaml")
+
+	output, err := runCLI(t, "", "list", "--output", "json", configFile)
+	if err != nil {
+		t.Fatalf("list failed: %v\nOutput: %s", err, output)
+	}
+
+	var configs []struct {
+		Name    string `json:"name"`
+		Sources []struct {
+			URL  string `json:"url"`
+			Dest string `json:"dest"`
+		} `json:"sources"`
+	}
+	if err := json.Unmarshal([]byte(output), &configs); err != nil {
+		t.Fatalf("Failed to parse list output as json: %v\nOutput: %s", err, output)
+	}
+	if len(configs) != 1 || configs[0].Name != "multiple-files" {
+		t.Errorf("Unexpected list output: %s", output)
+	}
+	if len(configs[0].Sources) != 2 {
+		t.Errorf("Expected 2 sources, got %d", len(configs[0].Sources))
+	}
+}
+
+// TestInvalidFlags tests that invalid flag values are rejected
+func TestInvalidFlags(t *testing.T) {
+	configFile := filepath.Join(fixturesPath, "simple.go.getter.yaml")
+
+	tests := [][]string{
+		{"--parallelism", "0", configFile},
+		{"--retries", "-1", configFile},
+		{"--output", "yaml", "list", configFile},
+		{"--log-level", "verbose", "list", configFile},
+		{"--log-format", "xml", "list", configFile},
+		{"--dest-conflicts", "ignore", "list", configFile},
+		{"--unknown-flag", configFile},
+	}
+
+	for _, args := range tests {
+		if _, err := runCLI(t, "", args...); err == nil {
+			t.Errorf("Expected %v to fail, but it succeeded", args)
+		}
+	}
+}
+
+// TestDestRelativeToConfig tests that relative destinations resolve against
+// the config file directory, or the working directory in compat mode
+func TestDestRelativeToConfig(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "hello")
+	}))
+	defer server.Close()
+
+	configDir := t.TempDir()
+	workDir := t.TempDir()
+
+	configFile := filepath.Join(configDir, "local.go.getter.yaml")
+	configData := fmt.Sprintf(`version: 1
+name: "local"
+sources:
+  - url: "%s/hello.txt"
+    dest: "output"
+`, server.URL)
+	if err := os.WriteFile(configFile, []byte(configData), 0644); err != nil {
+		t.Fatalf("Failed to create config file: %v", err)
+	}
+
+	output, err := runCLI(t, workDir, configFile)
+	if err != nil {
+		t.Fatalf("Command failed: %v\nOutput: %s", err, output)
+	}
+	if _, err := os.Stat(filepath.Join(configDir, "output", "hello.txt")); err != nil {
+		t.Errorf("Expected output next to the config file: %v", err)
+	}
+
+	output, err = runCLI(t, workDir, "--dest-relative-to-cwd", configFile)
+	if err != nil {
+		t.Fatalf("Command failed: %v\nOutput: %s", err, output)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "output", "hello.txt")); err != nil {
+		t.Errorf("Expected output in the working directory: %v", err)
+	}
+}
+
+// TestGetReport tests the json run report written with --report and --output json
+func TestGetReport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "hello")
+	}))
+	defer server.Close()
+
+	testDir := t.TempDir()
+	configFile := filepath.Join(testDir, "report.go.getter.yaml")
+	configData := fmt.Sprintf(`version: 1
+name: "report"
+sources:
+  - url: "%s/hello.txt"
+    dest: "output"
+`, server.URL)
+	if err := os.WriteFile(configFile, []byte(configData), 0644); err != nil {
+		t.Fatalf("Failed to create config file: %v", err)
+	}
+
+	reportFile := filepath.Join(testDir, "report.json")
+	output, err := runCLI(t, testDir, "get", "--output", "json", "--report", reportFile, configFile)
+	if err != nil {
+		t.Fatalf("Command failed: %v\nOutput: %s", err, output)
+	}
+
+	type report struct {
+		Status  string `json:"status"`
+		Configs []struct {
+			Name    string `json:"name"`
+			Sources []struct {
+				URL      string `json:"url"`
+				Status   string `json:"status"`
+				Attempts int    `json:"attempts"`
+				Bytes    int64  `json:"bytes"`
+			} `json:"sources"`
+		} `json:"configs"`
+	}
+
+	data, err := os.ReadFile(reportFile)
+	if err != nil {
+		t.Fatalf("Failed to read report: %v", err)
+	}
+
+	for name, raw := range map[string][]byte{"stdout": []byte(output), "report file": data} {
+		var r report
+		if err := json.Unmarshal(raw, &r); err != nil {
+			t.Fatalf("%s is not valid json: %v\n%s", name, err, raw)
+		}
+		if r.Status != "success" || len(r.Configs) != 1 || len(r.Configs[0].Sources) != 1 {
+			t.Fatalf("%s: unexpected report %+v", name, r)
+		}
+		src := r.Configs[0].Sources[0]
+		if src.Status != "success" || src.Attempts != 1 || src.Bytes != 5 {
+			t.Errorf("%s: unexpected source report %+v", name, src)
+		}
+	}
+}
+
+// TestLogFile tests structured logs written with --log-file and --log-format json
+func TestLogFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "hello")
+	}))
+	defer server.Close()
+
+	testDir := t.TempDir()
+	configFile := filepath.Join(testDir, "logs.go.getter.yaml")
+	configData := fmt.Sprintf(`version: 1
+name: "logs"
+sources:
+  - url: "%s/hello.txt"
+    dest: "output"
+`, server.URL)
+	if err := os.WriteFile(configFile, []byte(configData), 0644); err != nil {
+		t.Fatalf("Failed to create config file: %v", err)
+	}
+
+	logFile := filepath.Join(testDir, "run.log")
+	output, err := runCLI(t, testDir, "--log-format", "json", "--log-file", logFile, "--log-level", "debug", configFile)
+	if err != nil {
+		t.Fatalf("Command failed: %v\nOutput: %s", err, output)
+	}
+	if strings.Contains(output, "fetched source") {
+		t.Errorf("Expected logs in the log file only, got stdout: %s", output)
+	}
+
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+
+	var fetched bool
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Log line is not json: %v\n%s", err, line)
+		}
+		if record["msg"] == "fetched source" && record["config"] == "logs" && record["bytes"] == float64(5) {
+			fetched = true
+		}
+	}
+	if !fetched {
+		t.Errorf("Expected a fetched source record in the log file, got:\n%s", data)
+	}
+}
+
+// TestPolicyFile tests that sources denied by the --policy file fail without downloading
+func TestPolicyFile(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, "hello")
+	}))
+	defer server.Close()
+
+	testDir := t.TempDir()
+	configFile := filepath.Join(testDir, "policy.go.getter.yaml")
+	configData := fmt.Sprintf(`version: 1
+name: "policy"
+sources:
+  - url: "%s/hello.txt"
+    dest: "output"
+`, server.URL)
+	if err := os.WriteFile(configFile, []byte(configData), 0644); err != nil {
+		t.Fatalf("Failed to create config file: %v", err)
+	}
+
+	policyFile := filepath.Join(testDir, "policy.yaml")
+	if err := os.WriteFile(policyFile, []byte("allowed-schemes: [\"https\"]\n"), 0644); err != nil {
+		t.Fatalf("Failed to create policy file: %v", err)
+	}
+
+	output, err := runCLI(t, testDir, "--policy", policyFile, configFile)
+	if err == nil {
+		t.Fatalf("Expected the policy to deny the source\nOutput: %s", output)
+	}
+	if !strings.Contains(err.Error(), "denied by policy") {
+		t.Errorf("Expected a policy error, got: %v", err)
+	}
+	if requests != 0 {
+		t.Errorf("Expected no requests to the denied origin, got %d", requests)
+	}
+
+	output, err = runCLI(t, testDir, configFile)
+	if err != nil {
+		t.Fatalf("Command without policy failed: %v\nOutput: %s", err, output)
+	}
+}
+
+// TestOutputRoot tests that --output-root rejects destinations escaping it
+func TestOutputRoot(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "hello")
+	}))
+	defer server.Close()
+
+	testDir := t.TempDir()
+	configsDir := filepath.Join(testDir, "configs")
+	if err := os.Mkdir(configsDir, 0755); err != nil {
+		t.Fatalf("Failed to create configs directory: %v", err)
+	}
+	configFile := filepath.Join(configsDir, "escape.go.getter.yaml")
+	configData := fmt.Sprintf(`version: 1
+name: "escape"
+sources:
+  - url: "%[1]s/hello.txt"
+    dest: "vendor/hello"
+  - url: "%[1]s/hello.txt"
+    dest: "../escaped"
+`, server.URL)
+	if err := os.WriteFile(configFile, []byte(configData), 0644); err != nil {
+		t.Fatalf("Failed to create config file: %v", err)
+	}
+
+	for _, command := range []string{"get", "clean"} {
+		output, err := runCLI(t, testDir, command, "--output-root", configsDir, configFile)
+		if err == nil || !strings.Contains(err.Error(), "outside the output root") {
+			t.Errorf("%s: expected an output root error, got: %v\nOutput: %s", command, err, output)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(testDir, "escaped")); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing written outside the output root, got: %v", err)
+	}
+}
+
+// TestMigrateCommand tests rewriting a version 1 config to version 2
+func TestMigrateCommand(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := copyFixture(t, tmpDir, "multiple.go.getter.yaml")
+	original, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+
+	output, err := runCLI(t, "", "migrate", "--dry-run", configFile)
+	if err != nil {
+		t.Fatalf("migrate --dry-run failed: %v\nOutput: %s", err, output)
+	}
+	if !strings.Contains(output, "version: 2") {
+		t.Errorf("Expected the migrated config in dry-run output, got: %s", output)
+	}
+	if data, _ := os.ReadFile(configFile); string(data) != string(original) {
+		t.Error("migrate --dry-run modified the config file")
+	}
+
+	output, err = runCLI(t, "", "migrate", configFile)
+	if err != nil {
+		t.Fatalf("migrate failed: %v\nOutput: %s", err, output)
+	}
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatalf("Failed to read migrated config: %v", err)
+	}
+	for _, want := range []string{"# Test configuration with multiple sources", "version: 2", "  README:\n"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Expected %q in migrated config, got:\n%s", want, data)
+		}
+	}
+
+	output, err = runCLI(t, "", "validate", configFile)
+	if err != nil {
+		t.Fatalf("validate failed for migrated config: %v\nOutput: %s", err, output)
+	}
+
+	output, err = runCLI(t, "", "migrate", configFile)
+	if err != nil || !strings.Contains(output, "SKIP") {
+		t.Errorf("Expected migrating again to be skipped, got: %v\nOutput: %s", err, output)
+	}
+}
+
+// TestConfigFormats tests that directories are scanned for JSON, TOML and
+// HCL configuration files and that migrate only rewrites YAML files
+func TestConfigFormats(t *testing.T) {
+	formatsDir := filepath.Join(fixturesPath, "formats")
+
+	output, err := runCLI(t, "", "list", "--output", "json", formatsDir)
+	if err != nil {
+		t.Fatalf("list failed: %v\nOutput: %s", err, output)
+	}
+
+	var configs []struct {
+		Name    string `json:"name"`
+		Sources []struct {
+			Dest string `json:"dest"`
+		} `json:"sources"`
+	}
+	if err := json.Unmarshal([]byte(output), &configs); err != nil {
+		t.Fatalf("Failed to parse list output as json: %v\nOutput: %s", err, output)
+	}
+	var names []string
+	for _, cfg := range configs {
+		names = append(names, cfg.Name)
+		if len(cfg.Sources) != 1 {
+			t.Errorf("Expected 1 source in %s, got %d", cfg.Name, len(cfg.Sources))
+		}
+	}
+	if strings.Join(names, ",") != "hcl-format,json-format,toml-format" {
+		t.Errorf("Expected the HCL, JSON and TOML configs, got %v", names)
+	}
+
+	output, err = runCLI(t, "", "validate", formatsDir)
+	if err != nil {
+		t.Fatalf("validate failed: %v\nOutput: %s", err, output)
+	}
+
+	tmpDir := t.TempDir()
+	configFile := copyFixture(t, tmpDir, "formats/toml.go.getter.toml")
+	output, err = runCLI(t, "", "migrate", configFile)
+	if err == nil || !strings.Contains(output, "migrate only supports YAML files, not toml") {
+		t.Errorf("Expected migrate to reject a TOML file, got: %v\nOutput: %s", err, output)
+	}
+}